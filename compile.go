@@ -0,0 +1,78 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"fmt"
+
+	"github.com/juju/schema"
+	"gopkg.in/errgo.v1"
+)
+
+// Checker returns a checker that validates attribute maps against
+// the schema, built once so that it can be reused across many
+// coercions. The returned checker is safe for concurrent use. It
+// panics if a validation schema cannot be derived from s, so it is
+// intended for schemas known to be valid, typically package-level
+// variables; use Compile to obtain an error instead.
+func (s Fields) Checker() schema.Checker {
+	sfields, sdefaults, err := s.ValidationSchema()
+	if err != nil {
+		panic(fmt.Sprintf("invalid environschema.Fields: %v", err))
+	}
+	return schema.FieldMap(sfields, sdefaults)
+}
+
+// CompiledSchema holds a validation schema that has been derived
+// once from a Fields value so that it can be reused across many
+// coercions. It is safe for concurrent use.
+type CompiledSchema struct {
+	checker schema.Checker
+}
+
+// Compile returns a CompiledSchema derived from s. It is useful
+// when many value maps will be validated against the same schema,
+// for example by a server doing bulk import.
+func (s Fields) Compile() (*CompiledSchema, error) {
+	sfields, sdefaults, err := s.ValidationSchema()
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return &CompiledSchema{
+		checker: schema.FieldMap(sfields, sdefaults),
+	}, nil
+}
+
+// Coerce coerces the given values against the compiled schema,
+// returning the coerced attribute values.
+func (c *CompiledSchema) Coerce(values map[string]interface{}) (map[string]interface{}, error) {
+	v, err := c.checker.Coerce(values, nil)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// CoerceAll coerces each of the given records against the compiled
+// schema. It returns a slice of coerced records and a slice of
+// errors, both with one entry per record, so that a bulk import can
+// report partial failures: records[i] is nil exactly when errs[i]
+// is non-nil. The returned error slice is nil if every record
+// coerced successfully.
+func (c *CompiledSchema) CoerceAll(records []map[string]interface{}) ([]map[string]interface{}, []error) {
+	results := make([]map[string]interface{}, len(records))
+	var errs []error
+	for i, record := range records {
+		v, err := c.Coerce(record)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(records))
+			}
+			errs[i] = errgo.Mask(err)
+			continue
+		}
+		results[i] = v
+	}
+	return results, errs
+}
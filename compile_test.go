@@ -0,0 +1,78 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"testing"
+)
+
+func TestCompiledSchemaCoerce(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	compiled, err := fields.Compile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := compiled.Coerce(map[string]interface{}{"port": "8080"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v["port"] != 8080 {
+		t.Errorf("got %#v, want 8080", v["port"])
+	}
+}
+
+func TestCoerceAllPartialFailure(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	compiled, err := fields.Compile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := []map[string]interface{}{
+		{"port": 80},
+		{"port": "not a number"},
+		{"port": 443},
+	}
+	results, errs := compiled.CoerceAll(records)
+	if errs == nil {
+		t.Fatalf("expected errors for the failing record")
+	}
+	if len(results) != 3 || len(errs) != 3 {
+		t.Fatalf("got %d results and %d errors, want 3 of each", len(results), len(errs))
+	}
+	for _, i := range []int{0, 2} {
+		if errs[i] != nil {
+			t.Errorf("record %d: unexpected error: %v", i, errs[i])
+		}
+		if results[i] == nil {
+			t.Errorf("record %d: missing result", i)
+		}
+	}
+	if errs[1] == nil {
+		t.Errorf("record 1: expected error")
+	}
+	if results[1] != nil {
+		t.Errorf("record 1: got result %#v, want nil", results[1])
+	}
+}
+
+func TestCoerceAllSuccess(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	compiled, err := fields.Compile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, errs := compiled.CoerceAll([]map[string]interface{}{
+		{"port": 80},
+		{"port": 443},
+	})
+	if errs != nil {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
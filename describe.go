@@ -0,0 +1,93 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"sort"
+	"strings"
+)
+
+// FormDescription describes the fields of a schema in a shape
+// suitable for JSON serialization to a front end, with the fields
+// nested under their group sections so that the groups can be
+// rendered directly as collapsible panels.
+type FormDescription struct {
+	// Groups holds one entry for each group that has at
+	// least one field, ordered by group priority.
+	Groups []GroupDescription `json:"groups"`
+}
+
+// GroupDescription describes one group of fields.
+type GroupDescription struct {
+	// Name holds the group name as found in the schema.
+	Name string `json:"name"`
+
+	// DisplayName holds a name for the group suitable
+	// for displaying to a user.
+	DisplayName string `json:"displayName"`
+
+	// Fields holds the group's fields in prompting order.
+	Fields []FieldDescription `json:"fields"`
+}
+
+// FieldDescription describes a single field.
+type FieldDescription struct {
+	// Name holds the attribute name.
+	Name string `json:"name"`
+
+	Attr
+}
+
+// Describe returns a description of the fields in s grouped into
+// sections. Groups are ordered by decreasing priority (see
+// groupPriority) and the fields within each group are sorted into
+// the usual prompting order: secret fields after non-secret ones,
+// then by name.
+func (s Fields) Describe() FormDescription {
+	byGroup := make(map[Group][]attrWithName)
+	for name, attr := range s {
+		byGroup[attr.Group] = append(byGroup[attr.Group], attrWithName{
+			name: name,
+			Attr: attr,
+		})
+	}
+	groups := make([]Group, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		pi, pj := groupPriority(groups[i]), groupPriority(groups[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return groups[i] < groups[j]
+	})
+	var desc FormDescription
+	for _, g := range groups {
+		attrs := byGroup[g]
+		sort.Sort(attrsByGroup(attrs))
+		gdesc := GroupDescription{
+			Name:        string(g),
+			DisplayName: groupDisplayName(g),
+			Fields:      make([]FieldDescription, len(attrs)),
+		}
+		for i, attr := range attrs {
+			gdesc.Fields[i] = FieldDescription{
+				Name: attr.name,
+				Attr: attr.Attr,
+			}
+		}
+		desc.Groups = append(desc.Groups, gdesc)
+	}
+	return desc
+}
+
+// groupDisplayName returns a name for the given group
+// suitable for displaying to a user.
+func groupDisplayName(g Group) string {
+	if g == ProviderGroup {
+		return "Provider"
+	}
+	return strings.Title(string(g))
+}
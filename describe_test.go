@@ -0,0 +1,50 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	fields := Fields{
+		"region":   {Type: Tstring, Group: ProviderGroup},
+		"user":     {Type: Tstring, Group: AccountGroup},
+		"password": {Type: Tstring, Group: AccountGroup, Secret: true},
+		"timeout":  {Type: Tint, Group: EnvironGroup},
+	}
+	desc := fields.Describe()
+	if len(desc.Groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(desc.Groups))
+	}
+	// Groups are ordered by decreasing priority:
+	// provider, account, environ.
+	wantGroups := []struct {
+		name        string
+		displayName string
+		fields      []string
+	}{
+		{"", "Provider", []string{"region"}},
+		{"account", "Account", []string{"user", "password"}},
+		{"environ", "Environ", []string{"timeout"}},
+	}
+	for i, want := range wantGroups {
+		g := desc.Groups[i]
+		if g.Name != want.name {
+			t.Errorf("group %d: got name %q, want %q", i, g.Name, want.name)
+		}
+		if g.DisplayName != want.displayName {
+			t.Errorf("group %d: got display name %q, want %q", i, g.DisplayName, want.displayName)
+		}
+		if len(g.Fields) != len(want.fields) {
+			t.Errorf("group %d: got %d fields, want %d", i, len(g.Fields), len(want.fields))
+			continue
+		}
+		for j, name := range want.fields {
+			if g.Fields[j].Name != name {
+				t.Errorf("group %d field %d: got %q, want %q", i, j, g.Fields[j].Name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"reflect"
+	"sort"
+)
+
+// FieldChange describes a change to the value of one attribute,
+// as reported by Fields.DiffValues.
+type FieldChange struct {
+	// Name holds the attribute name.
+	Name string
+
+	// Old holds the previous value of the attribute,
+	// or nil if it was unset.
+	Old interface{}
+
+	// New holds the new value of the attribute,
+	// or nil if it has been unset.
+	New interface{}
+}
+
+// DiffValues compares two value maps against the schema and returns
+// a change entry, sorted by name, for each attribute whose value
+// differs between them. Values are coerced to their attribute's
+// canonical type before comparison so that equivalent
+// representations (such as "true" and true) do not show up as
+// changes; a value that cannot be coerced is compared as given.
+// Values of secret attributes are masked in the result.
+func (s Fields) DiffValues(old, new map[string]interface{}) []FieldChange {
+	var changes []FieldChange
+	for name, attr := range s {
+		oldVal, oldOk := coerced(attr, old, name)
+		newVal, newOk := coerced(attr, new, name)
+		if !oldOk && !newOk {
+			continue
+		}
+		if oldOk && newOk && reflect.DeepEqual(oldVal, newVal) {
+			continue
+		}
+		if attr.Secret {
+			if oldOk {
+				oldVal = "*****"
+			}
+			if newOk {
+				newVal = "*****"
+			}
+		}
+		changes = append(changes, FieldChange{
+			Name: name,
+			Old:  oldVal,
+			New:  newVal,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// coerced returns the coerced value of the named attribute in
+// values and whether the attribute is present at all.
+func coerced(attr Attr, values map[string]interface{}, name string) (interface{}, bool) {
+	v, ok := values[name]
+	if !ok {
+		return nil, false
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		return v, true
+	}
+	cv, err := checker.Coerce(v, nil)
+	if err != nil {
+		return v, true
+	}
+	return cv, true
+}
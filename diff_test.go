@@ -0,0 +1,65 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffValues(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+		"debug":  {Type: Tbool},
+		"token":  {Type: Tstring, Secret: true},
+	}
+	old := map[string]interface{}{
+		"region": "us-east-1",
+		"debug":  "true",
+		"token":  "old-secret",
+	}
+	new := map[string]interface{}{
+		"region": "eu-west-1",
+		// An equivalent representation is not a change.
+		"debug": true,
+		"token": "new-secret",
+	}
+	got := fields.DiffValues(old, new)
+	want := []FieldChange{{
+		Name: "region",
+		Old:  "us-east-1",
+		New:  "eu-west-1",
+	}, {
+		// Secret values are masked.
+		Name: "token",
+		Old:  "*****",
+		New:  "*****",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestDiffValuesSetAndUnset(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+		"debug":  {Type: Tbool},
+	}
+	got := fields.DiffValues(
+		map[string]interface{}{"debug": true},
+		map[string]interface{}{"region": "us-east-1"},
+	)
+	want := []FieldChange{{
+		Name: "debug",
+		Old:  true,
+		New:  nil,
+	}, {
+		Name: "region",
+		Old:  nil,
+		New:  "us-east-1",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnvMarkdown returns a markdown table documenting every environment
+// variable consulted by the schema, suitable for inclusion in an
+// "Environment Variables" section of generated documentation. There
+// is one row per environment variable, ordered by attribute name,
+// holding the variable name, the attribute it sets, the attribute
+// type and its description. Attributes with no associated environment
+// variable are omitted.
+func (s Fields) EnvMarkdown() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "| Variable | Attribute | Type | Description |")
+	fmt.Fprintln(&buf, "| --- | --- | --- | --- |")
+	for _, name := range names {
+		attr := s[name]
+		for _, v := range attr.allEnvVars() {
+			fmt.Fprintf(&buf, "| %s | %s | %s | %s |\n", v, name, attr.Type, markdownCell(attr.Description))
+		}
+	}
+	return buf.String()
+}
+
+// allEnvVars returns all the environment variables consulted
+// for the attribute, primary variable first.
+func (attr Attr) allEnvVars() []string {
+	var vars []string
+	if attr.EnvVar != "" {
+		vars = append(vars, attr.EnvVar)
+	}
+	for _, v := range attr.EnvVars {
+		if v != "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// markdownCell returns s adjusted so that it cannot
+// break out of a markdown table cell.
+func markdownCell(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	return strings.Replace(s, "|", "\\|", -1)
+}
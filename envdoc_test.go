@@ -0,0 +1,32 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"testing"
+)
+
+func TestEnvMarkdown(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Type:        Tstring,
+			Description: "cloud region",
+			EnvVar:      "TEST_REGION",
+			EnvVars:     []string{"TEST_REGION_ALT"},
+		},
+		// No environment variable, so no row.
+		"debug": {
+			Type:        Tbool,
+			Description: "enable debugging",
+		},
+	}
+	got := fields.EnvMarkdown()
+	want := "| Variable | Attribute | Type | Description |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| TEST_REGION | region | string | cloud region |\n" +
+		"| TEST_REGION_ALT | region | string | cloud region |\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
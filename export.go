@@ -0,0 +1,53 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ExportEnv returns shell commands that export each attribute in
+// values with a declared environment variable, one
+//
+//	export VAR='value'
+//
+// line per attribute in name order, with the values coerced and
+// shell-quoted. It is the inverse of reading defaults from the
+// environment: the output can be evaluated by the user's shell to
+// reproduce the configuration. Attributes without an env var are
+// skipped. Secret values are exported like any other, since the
+// output is destined for the user's own shell, but are preceded by
+// a warning comment.
+func (s Fields) ExportEnv(values map[string]interface{}) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if attr, ok := s[name]; ok && attr.EnvVar != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		attr := s[name]
+		val := values[name]
+		if checker, err := attr.Checker(); err == nil {
+			if cv, err := checker.Coerce(val, nil); err == nil {
+				val = cv
+			}
+		}
+		if attr.Secret {
+			fmt.Fprintf(&buf, "# warning: %s holds a secret value\n", attr.EnvVar)
+		}
+		fmt.Fprintf(&buf, "export %s=%s\n", attr.EnvVar, shellQuote(fmt.Sprint(val)))
+	}
+	return buf.String()
+}
+
+// shellQuote returns s single-quoted for use in shell commands.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,43 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportEnv(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, EnvVar: "TEST_REGION"},
+		"motd":   {Type: Tstring, EnvVar: "TEST_MOTD"},
+		"debug":  {Type: Tbool},
+	}
+	got := fields.ExportEnv(map[string]interface{}{
+		"region": "us-east-1",
+		"motd":   "it's here",
+		// No declared env var, so this is skipped.
+		"debug": true,
+	})
+	want := "export TEST_MOTD='it'\\''s here'\n" +
+		"export TEST_REGION='us-east-1'\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExportEnvSecretWarning(t *testing.T) {
+	fields := Fields{
+		"token": {Type: Tstring, EnvVar: "TEST_TOKEN", Secret: true},
+	}
+	got := fields.ExportEnv(map[string]interface{}{
+		"token": "tok-123",
+	})
+	if !strings.Contains(got, "# warning: TEST_TOKEN holds a secret value\n") {
+		t.Errorf("output %q does not warn about the secret value", got)
+	}
+	if !strings.Contains(got, "export TEST_TOKEN='tok-123'\n") {
+		t.Errorf("output %q does not export the secret value", got)
+	}
+}
@@ -0,0 +1,1460 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+// Package environschema implements a way to specify
+// configuration attributes for Juju environments.
+package environschema
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/schema"
+	"gopkg.in/errgo.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// Fields holds a map from attribute name to
+// information about that attribute.
+type Fields map[string]Attr
+
+// Attr describes the type and properties of a configuration attribute.
+type Attr struct {
+	// Description describes the attribute's meaning.
+	Description string `json:"description" yaml:"description"`
+
+	// Type holds the type of the attribute value.
+	Type FieldType `json:"type" yaml:"type"`
+
+	// Group holds the group that the attribute belongs to.
+	// All attributes within a Fields that have the same Group
+	// attribute are considered to be part of the same group.
+	Group Group `json:"group,omitempty" yaml:"group,omitempty"`
+
+	// Immutable specifies whether the attribute cannot
+	// be changed once set.
+	Immutable bool `json:"immutable,omitempty" yaml:"immutable,omitempty"`
+
+	// Mandatory specifies whether the attribute
+	// must be provided.
+	Mandatory bool `json:"mandatory,omitempty" yaml:"mandatory,omitempty"`
+
+	// Secret specifies whether the attribute should be
+	// considered secret.
+	Secret bool `json:"is-secret,omitempty" yaml:"is-secret,omitempty"`
+
+	// Destructive specifies whether changing the attribute
+	// can destroy data. Fillers that summarize and confirm
+	// the entered values before applying them will call out
+	// destructive attributes and require explicit
+	// acknowledgement.
+	Destructive bool `json:"destructive,omitempty" yaml:"destructive,omitempty"`
+
+	// RemovedIn holds the version in which the attribute
+	// will be removed, if it is scheduled for removal.
+	// Supplying a value for such an attribute produces a
+	// warning (see Fields.Warnings).
+	RemovedIn string `json:"removed-in,omitempty" yaml:"removed-in,omitempty"`
+
+	// EnvVar holds the environment variable
+	// that will be used to obtain the default value
+	// if it isn't specified.
+	EnvVar string `json:"env-var,omitempty" yaml:"env-var,omitempty"`
+
+	// EnvVars holds additional environment
+	// variables to be used if the variable in EnvVar is
+	// not set, tried in order.
+	EnvVars []string `json:"env-vars,omitempty" yaml:"env-vars,omitempty"`
+
+	// Example holds an example value for the attribute
+	// that can be used to produce a plausible-looking
+	// entry for the attribute without necessarily using
+	// it as a default value.
+	Example interface{} `json:"example,omitempty" yaml:"example,omitempty"`
+
+	// Default holds the default value of the attribute, if any.
+	// It must conform to the attribute type. Note that defaults
+	// are not applied by ValidationSchema - it is up to the
+	// client to apply them as required.
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// DefaultFromField names another attribute whose entered
+	// value is used as the default for this attribute when the
+	// schema is filled interactively. The named attribute must
+	// be prompted for before this one - see form.PromptingFiller.
+	// It has no effect on ValidationSchema.
+	DefaultFromField string `json:"default-from-field,omitempty" yaml:"default-from-field,omitempty"`
+
+	// Values holds the set of all possible values of the attribute.
+	Values []interface{} `json:"values,omitempty" yaml:"values,omitempty"`
+
+	// CaseInsensitive specifies that a value is matched against
+	// Values ignoring case, with the canonical value from Values
+	// used as the result. It is only honoured for attributes of
+	// type Tstring; other types ignore it.
+	CaseInsensitive bool `json:"case-insensitive,omitempty" yaml:"case-insensitive,omitempty"`
+
+	// KeyPattern holds a regular expression (in Go regexp
+	// syntax) that every key of the attribute value must
+	// match. It is only valid for attributes of type Tattrs.
+	KeyPattern string `json:"key-pattern,omitempty" yaml:"key-pattern,omitempty"`
+
+	// ValueSeparator holds a separator that makes each value
+	// of a Tattrs attribute hold a list: values are split on
+	// the separator and the canonical Go type of the attribute
+	// becomes map[string][]string. It is only valid for
+	// attributes of type Tattrs.
+	ValueSeparator string `json:"value-separator,omitempty" yaml:"value-separator,omitempty"`
+
+	// AllowFileRef specifies that a value given as a string
+	// starting with "@" names a YAML file holding the actual
+	// value, following the common command line convention.
+	// The file is read and parsed when the value is coerced.
+	// It is only valid for attributes of type Tattrs.
+	AllowFileRef bool `json:"allow-file-ref,omitempty" yaml:"allow-file-ref,omitempty"`
+
+	// ExampleInError specifies that the Example value, if
+	// there is one, is appended to errors from failed
+	// coercion of the attribute value, to guide correction.
+	ExampleInError bool `json:"example-in-error,omitempty" yaml:"example-in-error,omitempty"`
+
+	// ExpandEnv specifies that $VAR and ${VAR} references in
+	// the attribute value are expanded against the process
+	// environment when the value is coerced. It is only valid
+	// for attributes of type Tstring.
+	ExpandEnv bool `json:"expand-env,omitempty" yaml:"expand-env,omitempty"`
+
+	// DurationUnit holds a unit name ("ms", "s" or "m") that
+	// makes a duration-typed attribute coerce to an integer
+	// count of that unit instead of a time.Duration. A
+	// duration that is not a whole number of the unit is an
+	// error. It is only valid for duration-typed attributes.
+	DurationUnit string `json:"duration-unit,omitempty" yaml:"duration-unit,omitempty"`
+
+	// Step holds a value that the attribute value must be a
+	// multiple of. If it is zero, no constraint is applied.
+	// It is only valid for attributes of type Tint or Tuint.
+	Step int `json:"step,omitempty" yaml:"step,omitempty"`
+
+	// MandatoryIf names another attribute whose presence makes
+	// this attribute mandatory: if the named attribute has a
+	// non-empty value, a value must also be supplied for this
+	// one. It is checked by ValidateConditional rather than by
+	// the plain validation schema.
+	MandatoryIf string `json:"mandatory-if,omitempty" yaml:"mandatory-if,omitempty"`
+
+	// Order holds an explicit position for the attribute when
+	// fields are sorted for display, taking precedence over the
+	// alphabetical ordering by name. Attributes with a non-zero
+	// Order come before those without one, lower values first;
+	// attributes with equal or zero Order fall back to the usual
+	// ordering. It does not override the grouping of fields.
+	Order int `json:"order,omitempty" yaml:"order,omitempty"`
+
+	// Deprecated specifies that the attribute is still supported
+	// but being phased out, so that tools can warn users away
+	// from it without breaking existing configurations.
+	Deprecated bool `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+
+	// DeprecatedMessage optionally holds extra guidance shown
+	// with deprecation warnings, typically naming a replacement
+	// attribute. It is only meaningful when Deprecated is true.
+	DeprecatedMessage string `json:"deprecated-message,omitempty" yaml:"deprecated-message,omitempty"`
+
+	// Multiline specifies that the attribute value may span
+	// several lines, for example a PEM certificate or an SSH
+	// key, and that interactive prompters should keep reading
+	// until a terminating sentinel rather than stopping at the
+	// first newline. Internal newlines are preserved in the
+	// value. It is only meaningful for string-valued attributes.
+	Multiline bool `json:"multiline,omitempty" yaml:"multiline,omitempty"`
+
+	// Pattern holds a regular expression (in Go regexp syntax)
+	// that the attribute value must match. The pattern is
+	// compiled when the validation schema is built, so an
+	// invalid pattern surfaces then rather than at coerce time.
+	// It is only valid for attributes of type Tstring.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	// Min and Max hold inclusive bounds on the attribute
+	// value, applied after coercion. Either may be set
+	// independently of the other; a nil bound is not applied.
+	// They are only valid for attributes of type Tint or Tuint.
+	Min *int `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *int `json:"max,omitempty" yaml:"max,omitempty"`
+
+	// TreatNullAsUnset specifies that a value given as nil
+	// (JSON null) or as one of the sentinel strings "null" or
+	// "none" means the attribute is explicitly unset, so it is
+	// omitted from coerced results rather than producing an
+	// empty value (see Fields.PruneNull).
+	TreatNullAsUnset bool `json:"treat-null-as-unset,omitempty" yaml:"treat-null-as-unset,omitempty"`
+
+	// Multi specifies that the attribute holds a subset of
+	// Values rather than a single one of them. A value is
+	// specified as a comma-separated string and its canonical
+	// Go type is []string. It is only valid when Values is
+	// non-empty.
+	Multi bool `json:"multi,omitempty" yaml:"multi,omitempty"`
+}
+
+// Group describes the grouping of attributes.
+type Group string
+
+// The following constants are the initially defined group values.
+const (
+	// JujuGroup groups attributes defined by Juju that may
+	// not be specified by a user.
+	JujuGroup Group = "juju"
+
+	// EnvironGroup groups attributes that are defined across all
+	// possible Juju environments.
+	EnvironGroup Group = "environ"
+
+	// AccountGroup groups attributes that define a user account
+	// used by a provider.
+	AccountGroup Group = "account"
+
+	// ProviderGroup groups attributes defined by the provider
+	// that are not account credentials. This is also the default
+	// group.
+	ProviderGroup Group = ""
+)
+
+// FieldType describes the type of an attribute value.
+type FieldType string
+
+// The following constants are the possible type values.
+// The "canonical Go type" is the type that the will be
+// the result of a successful Coerce call.
+const (
+	// Tstring represents a string type. Its canonical Go type is string.
+	Tstring FieldType = "string"
+
+	// Tbool represents a boolean type. Its canonical Go type is bool.
+	Tbool FieldType = "bool"
+
+	// Tint represents an integer type. Its canonical Go type is int.
+	Tint FieldType = "int"
+
+	// Tattrs represents an attribute map. Its canonical Go type is
+	// map[string]string.
+	Tattrs FieldType = "attrs"
+
+	// Tunstring represents a string that may have been specified
+	// as a non-string value. Its canonical Go type is string.
+	Tunstring FieldType = "unstring"
+
+	// Tduration represents a duration in the format accepted
+	// by time.ParseDuration, such as "30s" or "5m". Its
+	// canonical Go type is time.Duration.
+	Tduration FieldType = "duration"
+
+	// Tlist represents a homogeneous list of strings, which
+	// may be specified as a list or as a single comma-separated
+	// string. Its canonical Go type is []string.
+	Tlist FieldType = "list"
+
+	// Tpath represents a file name whose contents are used as
+	// the value: coercion reads the named file, resolving
+	// relative paths against the current working directory.
+	// Its canonical Go type is string.
+	Tpath FieldType = "path"
+
+	// Tuint represents a non-negative integer, for attributes
+	// such as ports, sizes and counts that can never sensibly
+	// be negative. Its canonical Go type is int.
+	Tuint FieldType = "uint"
+)
+
+// IsValid reports whether t is a type known to this package,
+// so that a checker can be derived for it.
+func (t FieldType) IsValid() bool {
+	return checkers[t] != nil
+}
+
+// checkers maps from FieldType to the checker
+// that will be used to coerce values of that type.
+var checkers = map[FieldType]schema.Checker{
+	Tstring:   schema.String(),
+	Tattrs:    attrsC{},
+	Tbool:     schema.Bool(),
+	Tint:      intC{},
+	Tunstring: unstringC{},
+	Tduration: durationC{},
+	Tlist:     listC{},
+	Tpath:     pathC{},
+	Tuint:     uintC{},
+}
+
+// Checker returns a checker that can be used to coerce values into the
+// type of the attribute. It returns an error if the attribute type is
+// not valid.
+func (attr Attr) Checker() (schema.Checker, error) {
+	return attr.checkerWith(nil)
+}
+
+// checkerWith returns the attribute's checker, with checkers in
+// overrides replacing the built-in checker for their field type.
+func (attr Attr) checkerWith(overrides map[FieldType]schema.Checker) (schema.Checker, error) {
+	checker, overridden := overrides[attr.Type]
+	if !overridden {
+		checker = checkers[attr.Type]
+	}
+	if checker == nil {
+		return nil, fmt.Errorf("invalid type %q", attr.Type)
+	}
+	// The attrs options replace the built-in Tattrs checker, so
+	// they do not apply when it has been overridden.
+	if !overridden && (attr.KeyPattern != "" || attr.AllowFileRef || attr.ValueSeparator != "") {
+		if attr.Type != Tattrs {
+			return nil, fmt.Errorf("attrs option specified on non-attrs type %q", attr.Type)
+		}
+		c := attrsC{
+			allowFileRef: attr.AllowFileRef,
+			valueSep:     attr.ValueSeparator,
+		}
+		if attr.KeyPattern != "" {
+			pat, err := regexp.Compile(attr.KeyPattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key pattern %q: %v", attr.KeyPattern, err)
+			}
+			c.keyPattern = pat
+		}
+		checker = c
+	}
+	if attr.DurationUnit != "" {
+		if attr.Type != Tduration {
+			return nil, fmt.Errorf("duration unit specified on non-duration type %q", attr.Type)
+		}
+		unit, ok := durationUnits[attr.DurationUnit]
+		if !ok {
+			return nil, fmt.Errorf("unknown duration unit %q", attr.DurationUnit)
+		}
+		checker = durationUnitC{
+			unit:    unit,
+			name:    attr.DurationUnit,
+			checker: checker,
+		}
+	}
+	if attr.ExpandEnv {
+		if attr.Type != Tstring {
+			return nil, fmt.Errorf("expand-env specified on non-string type %q", attr.Type)
+		}
+		checker = expandEnvC{
+			checker: checker,
+		}
+	}
+	if attr.Step != 0 {
+		if attr.Type != Tint && attr.Type != Tuint {
+			return nil, fmt.Errorf("step specified on non-integer type %q", attr.Type)
+		}
+		checker = multipleOfC{
+			step:    attr.Step,
+			checker: checker,
+		}
+	}
+	if attr.Pattern != "" {
+		if attr.Type != Tstring {
+			return nil, fmt.Errorf("pattern specified on non-string type %q", attr.Type)
+		}
+		pat, err := regexp.Compile(attr.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", attr.Pattern, err)
+		}
+		checker = patternC{
+			pattern: pat,
+			checker: checker,
+		}
+	}
+	if attr.Min != nil || attr.Max != nil {
+		if attr.Type != Tint && attr.Type != Tuint {
+			return nil, fmt.Errorf("min/max specified on non-integer type %q", attr.Type)
+		}
+		if attr.Min != nil && attr.Max != nil && *attr.Min > *attr.Max {
+			return nil, fmt.Errorf("min %d is greater than max %d", *attr.Min, *attr.Max)
+		}
+		checker = rangeC{
+			min:     attr.Min,
+			max:     attr.Max,
+			checker: checker,
+		}
+	}
+	foldCase := attr.CaseInsensitive && attr.Type == Tstring
+	switch {
+	case len(attr.Values) == 0 && attr.Multi:
+		return nil, fmt.Errorf("multi specified without values")
+	case attr.Multi:
+		var err error
+		if checker, err = subsetOfValues(checker, attr.Values, foldCase); err != nil {
+			return nil, err
+		}
+	case len(attr.Values) > 0:
+		var err error
+		if checker, err = oneOfValues(checker, attr.Values, foldCase); err != nil {
+			return nil, err
+		}
+	}
+	if attr.ExampleInError && attr.Example != nil {
+		checker = exampleErrC{
+			checker: checker,
+			example: attr.Example,
+		}
+	}
+	return checker, nil
+}
+
+// IsDefault reports whether the given value is equal to the
+// attribute's default value. Both the value and the default are
+// coerced to the attribute's canonical type before comparison.
+// If the attribute has no default, or either value cannot be
+// coerced, it returns false.
+func (attr Attr) IsDefault(value interface{}) bool {
+	if attr.Default == nil {
+		return false
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		return false
+	}
+	v, err := checker.Coerce(value, nil)
+	if err != nil {
+		return false
+	}
+	d, err := checker.Coerce(attr.Default, nil)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(v, d)
+}
+
+// attrsC implements the checker for the Tattrs type.
+// It accepts a map from string to string, a slice of
+// key=value strings or a single string holding
+// space-separated key=value pairs. Its canonical
+// form is map[string]string.
+type attrsC struct {
+	// keyPattern, if non-nil, holds a pattern that
+	// every key of the map must match.
+	keyPattern *regexp.Regexp
+
+	// allowFileRef holds whether a string value starting
+	// with "@" is interpreted as the name of a YAML file
+	// holding the attribute map.
+	allowFileRef bool
+
+	// valueSep, if non-empty, holds a separator on which
+	// each value is split, making the canonical form of
+	// the map map[string][]string.
+	valueSep string
+}
+
+var (
+	attrMapChecker   = schema.Map(schema.String(), schema.String())
+	attrSliceChecker = schema.List(schema.String())
+)
+
+// Coerce implements schema.Checker.Coerce.
+func (c attrsC) Coerce(v interface{}, path []string) (interface{}, error) {
+	if v == nil {
+		return nil, errgo.Newf("%sunexpected nil value", pathPrefix(path))
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.String:
+		s, err := schema.String().Coerce(v, path)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		if name := s.(string); c.allowFileRef && strings.HasPrefix(name, "@") {
+			result, err := readAttrsFile(name[1:])
+			if err != nil {
+				return nil, errgo.Notef(err, "%scannot read attrs", pathPrefix(path))
+			}
+			return c.finish(result, path)
+		}
+		result, err := parseKeyValues(strings.Fields(s.(string)))
+		if err != nil {
+			return nil, errgo.Newf("%s%v", pathPrefix(path), err)
+		}
+		return c.finish(result, path)
+	case reflect.Slice:
+		slice0, err := attrSliceChecker.Coerce(v, path)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		slice := slice0.([]interface{})
+		fields := make([]string, len(slice))
+		for i, f := range slice {
+			fields[i] = f.(string)
+		}
+		result, err := parseKeyValues(fields)
+		if err != nil {
+			return nil, errgo.Newf("%s%v", pathPrefix(path), err)
+		}
+		return c.finish(result, path)
+	case reflect.Map:
+		imap0, err := attrMapChecker.Coerce(v, path)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		imap := imap0.(map[interface{}]interface{})
+		result := make(map[string]string)
+		for k, v := range imap {
+			result[k.(string)] = v.(string)
+		}
+		return c.finish(result, path)
+	}
+	return nil, errgo.Newf("%sunexpected type for value, got %T(%v)", pathPrefix(path), v, v)
+}
+
+// finish applies the attrs options to a parsed map: it checks
+// that every key matches the key pattern, if one is set, and
+// splits each value on the value separator, if one is set.
+func (c attrsC) finish(m map[string]string, path []string) (interface{}, error) {
+	if c.keyPattern != nil {
+		for k := range m {
+			if !c.keyPattern.MatchString(k) {
+				return nil, fmt.Errorf("%sinvalid key %q", pathPrefix(path), k)
+			}
+		}
+	}
+	if c.valueSep != "" {
+		result := make(map[string][]string, len(m))
+		for k, v := range m {
+			result[k] = strings.Split(v, c.valueSep)
+		}
+		return result, nil
+	}
+	return m, nil
+}
+
+// readAttrsFile reads and parses the YAML file with the given
+// name, which must hold a mapping from string to string.
+func readAttrsFile(file string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	var result map[string]string
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, errgo.Notef(err, "cannot parse %q", file)
+	}
+	return result, nil
+}
+
+// parseKeyValues parses the given strings, each of which
+// should hold a key=value pair, into a map.
+func parseKeyValues(fields []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, f := range fields {
+		i := strings.Index(f, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf(`expected "key=value", got %q`, f)
+		}
+		key, value := f[0:i], f[i+1:]
+		if _, ok := result[key]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// readFile is used to read the file named by a Tpath value.
+// It is a variable so that it can be replaced for testing.
+var readFile = ioutil.ReadFile
+
+// pathC is the checker for the Tpath field type. It treats the
+// value as a file name, reads the file and coerces to its
+// contents as a string.
+type pathC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c pathC) Coerce(v interface{}, path []string) (interface{}, error) {
+	name, ok := v.(string)
+	if !ok {
+		return nil, errgo.Newf("%sunexpected type for value, got %T(%v)", pathPrefix(path), v, v)
+	}
+	data, err := readFile(name)
+	if err != nil {
+		return nil, errgo.Newf("%scannot read file %q: %v", pathPrefix(path), name, err)
+	}
+	return string(data), nil
+}
+
+// listC is the checker for the Tlist field type. It accepts a
+// slice of strings or a single comma-separated string, trimming
+// whitespace around each element, and coerces to []string. An
+// empty string yields an empty slice rather than nil.
+type listC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c listC) Coerce(v interface{}, path []string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return []string{}, nil
+		}
+		elems := strings.Split(v, ",")
+		result := make([]string, len(elems))
+		for i, e := range elems {
+			result[i] = strings.TrimSpace(e)
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, errgo.Newf("%sunexpected type for list element, got %T(%v)", pathPrefix(path), e, e)
+			}
+			result[i] = s
+		}
+		return result, nil
+	case []string:
+		if v == nil {
+			return []string{}, nil
+		}
+		return v, nil
+	}
+	return nil, errgo.Newf("%sunexpected type for value, got %T(%v)", pathPrefix(path), v, v)
+}
+
+// durationC is the checker for the Tduration field type. It
+// accepts a string in the format accepted by time.ParseDuration,
+// or a time.Duration directly, and coerces to a time.Duration.
+type durationC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c durationC) Coerce(v interface{}, path []string) (interface{}, error) {
+	switch v := v.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, nil
+		}
+	}
+	return nil, errgo.Newf("%sexpected duration, got %T(%v)", pathPrefix(path), v, v)
+}
+
+// durationUnits maps the supported duration unit
+// names to their durations.
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+}
+
+// durationUnitC converts a time.Duration produced by the
+// underlying checker into an integer count of a given unit.
+// A duration that is not a whole number of the unit is
+// an error.
+type durationUnitC struct {
+	unit    time.Duration
+	name    string
+	checker schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c durationUnitC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	d := v.(time.Duration)
+	if d%c.unit != 0 {
+		return nil, fmt.Errorf("%sduration %v is not a whole number of %s", pathPrefix(path), d, c.name)
+	}
+	return int(d / c.unit), nil
+}
+
+// exampleErrC appends the attribute's example value to
+// errors from the underlying checker.
+type exampleErrC struct {
+	checker schema.Checker
+	example interface{}
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c exampleErrC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return nil, fmt.Errorf("%v (example: %v)", err, c.example)
+	}
+	return v, nil
+}
+
+// expandEnvC expands environment variable references
+// in a string value after coercion.
+type expandEnvC struct {
+	checker schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c expandEnvC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	return os.ExpandEnv(v.(string)), nil
+}
+
+// multipleOfC checks that an integer value is a
+// multiple of a given step.
+type multipleOfC struct {
+	step    int
+	checker schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c multipleOfC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	if v.(int)%c.step != 0 {
+		return nil, fmt.Errorf("%svalue must be a multiple of %d, got %v", pathPrefix(path), c.step, v)
+	}
+	return v, nil
+}
+
+// patternC checks that a string value matches a regular
+// expression compiled when the checker was built.
+type patternC struct {
+	pattern *regexp.Regexp
+	checker schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c patternC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	if s := v.(string); !c.pattern.MatchString(s) {
+		return nil, fmt.Errorf("%s%q does not match %s", pathPrefix(path), s, c.pattern)
+	}
+	return v, nil
+}
+
+// intC is the checker for the Tint field type. Non-string values
+// are coerced as schema.ForceInt does; strings are parsed with
+// strconv.ParseInt in base 0, so hex ("0xFF"), octal ("0755" or
+// "0o755") and binary ("0b101") forms are accepted as well as
+// plain decimal.
+type intC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c intC) Coerce(v interface{}, path []string) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return schema.ForceInt().Coerce(v, path)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return nil, fmt.Errorf("%svalue %s out of range", pathPrefix(path), s)
+		}
+		return nil, fmt.Errorf("%sexpected int, got string(%q)", pathPrefix(path), s)
+	}
+	return int(n), nil
+}
+
+// uintC is the checker for the Tuint field type. It accepts the
+// same forms as the Tint checker but rejects negative values.
+type uintC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c uintC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := intC{}.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	if n := v.(int); n < 0 {
+		return nil, fmt.Errorf("%svalue must be non-negative, got %d", pathPrefix(path), n)
+	}
+	return v, nil
+}
+
+// rangeC checks that an integer value lies within
+// inclusive bounds. A nil bound is not applied.
+type rangeC struct {
+	min     *int
+	max     *int
+	checker schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c rangeC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	n := v.(int)
+	switch {
+	case c.min != nil && c.max != nil && (n < *c.min || n > *c.max):
+		return nil, fmt.Errorf("%svalue %d out of range [%d, %d]", pathPrefix(path), n, *c.min, *c.max)
+	case c.min != nil && n < *c.min:
+		return nil, fmt.Errorf("%svalue %d is less than minimum %d", pathPrefix(path), n, *c.min)
+	case c.max != nil && n > *c.max:
+		return nil, fmt.Errorf("%svalue %d is greater than maximum %d", pathPrefix(path), n, *c.max)
+	}
+	return v, nil
+}
+
+// unstringC implements the Tunstring checker - it accepts
+// any value that can be converted to a string and converts
+// it, preserving the default formatting of the value.
+type unstringC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c unstringC) Coerce(v interface{}, path []string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int, int64, float64, bool:
+		return fmt.Sprint(v), nil
+	}
+	return nil, errgo.Newf("%sunexpected type for value, got %T(%v)", pathPrefix(path), v, v)
+}
+
+// PresenceBool returns a checker that coerces values like the
+// standard Tbool checker except that an empty string coerces to
+// true. This implements flag-like semantics where the mere
+// presence of a value implies truth, and is intended for use
+// by flag-based fillers - it is never the default behaviour
+// of a Tbool attribute.
+func PresenceBool() schema.Checker {
+	return presenceBoolC{}
+}
+
+// presenceBoolC implements the checker returned by PresenceBool.
+type presenceBoolC struct{}
+
+// Coerce implements schema.Checker.Coerce.
+func (c presenceBoolC) Coerce(v interface{}, path []string) (interface{}, error) {
+	if s, ok := v.(string); ok && s == "" {
+		return true, nil
+	}
+	return checkers[Tbool].Coerce(v, path)
+}
+
+// oneOfValues returns a checker that coerces its value
+// using the given checker, then checks that the
+// resulting value is equal to one of the given values.
+// If foldCase is true, string values are matched ignoring
+// case and the canonical value is returned.
+func oneOfValues(checker schema.Checker, values []interface{}, foldCase bool) (schema.Checker, error) {
+	cvalues := make([]interface{}, len(values))
+	for i, v := range values {
+		cv, err := checker.Coerce(v, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enumerated value: %v", err)
+		}
+		cvalues[i] = cv
+	}
+	return oneOfValuesC{
+		vals:     cvalues,
+		foldCase: foldCase,
+		checker:  checker,
+	}, nil
+}
+
+type oneOfValuesC struct {
+	vals     []interface{}
+	foldCase bool
+	checker  schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c oneOfValuesC) Coerce(v interface{}, path []string) (interface{}, error) {
+	v, err := c.checker.Coerce(v, path)
+	if err != nil {
+		return v, err
+	}
+	for _, allow := range c.vals {
+		if allow == v {
+			return v, nil
+		}
+		if c.foldCase {
+			if as, ok := allow.(string); ok {
+				if vs, ok := v.(string); ok && strings.EqualFold(as, vs) {
+					return allow, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%sexpected one of %v, got %#v", pathPrefix(path), c.vals, v)
+}
+
+// subsetOfValues returns a checker for a Multi attribute that
+// accepts a comma-separated string, coerces each element using the
+// given checker and checks that each one is equal to one of the
+// given values. The result of a successful coercion is a []string.
+func subsetOfValues(checker schema.Checker, values []interface{}, foldCase bool) (schema.Checker, error) {
+	one, err := oneOfValues(checker, values, foldCase)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return subsetOfValuesC{
+		one: one,
+	}, nil
+}
+
+type subsetOfValuesC struct {
+	one schema.Checker
+}
+
+// Coerce implements schema.Checker.Coerce.
+func (c subsetOfValuesC) Coerce(v interface{}, path []string) (interface{}, error) {
+	s, err := schema.String().Coerce(v, path)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	var result []string
+	seen := make(map[string]bool)
+	for _, elem := range strings.Split(s.(string), ",") {
+		elem = strings.TrimSpace(elem)
+		ev, err := c.one.Coerce(elem, path)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		es := fmt.Sprint(ev)
+		if seen[es] {
+			return nil, fmt.Errorf("%sduplicate value %q", pathPrefix(path), es)
+		}
+		seen[es] = true
+		result = append(result, es)
+	}
+	return result, nil
+}
+
+// pathPrefix returns an error message prefix holding
+// the concatenation of the path elements. If path
+// starts with a ".", the dot is omitted.
+func pathPrefix(path []string) string {
+	if p := pathName(path); p != "" {
+		return p + ": "
+	}
+	return ""
+}
+
+// pathName returns the name of the given path.
+func pathName(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	if path[0] == "." {
+		return strings.Join(path[1:], "")
+	}
+	return strings.Join(path, "")
+}
+
+// ValidationSchema returns values suitable for passing to
+// schema.FieldMap to create a schema.Checker that will validate the
+// given fields. It will return an error if the fields are invalid.
+//
+// The Defaults return value will contain entries for all non-mandatory
+// attributes set to schema.Omit. It is the responsibility of the
+// client to set any actual default values as required.
+func (s Fields) ValidationSchema() (schema.Fields, schema.Defaults, error) {
+	return s.ValidationSchemaWithCheckers(nil)
+}
+
+// ValidationSchemaWithCheckers is like ValidationSchema except that
+// any checker in overrides replaces the built-in checker for its
+// field type for the duration of the call, allowing per-call
+// customization (for example a stricter Tstring checker) without
+// mutating the schema or a global registry.
+func (s Fields) ValidationSchemaWithCheckers(overrides map[FieldType]schema.Checker) (schema.Fields, schema.Defaults, error) {
+	fields := make(schema.Fields)
+	defaults := make(schema.Defaults)
+	for name, attr := range s {
+		checker, err := attr.checkerWith(overrides)
+		if err != nil {
+			return nil, nil, errgo.Notef(err, "%s", name)
+		}
+		// A Tpath example names a file that need not exist
+		// where the schema is built, so it cannot be checked
+		// by coercion.
+		if attr.Example != nil && attr.Type != Tpath {
+			if _, err := checker.Coerce(attr.Example, nil); err != nil {
+				return nil, nil, errgo.Notef(err, "%s: invalid example value", name)
+			}
+		}
+		fields[name] = checker
+		if !attr.Mandatory {
+			defaults[name] = schema.Omit
+		}
+	}
+	return fields, defaults, nil
+}
+
+// ValidationSchemaWithDefaults is like ValidationSchema except that
+// an attribute with a Default value has the coerced default used as
+// its schema default instead of schema.Omit, so that coercion fills
+// in defaults for missing attributes. An invalid default fails at
+// schema-build time with an "invalid default value" error.
+func (s Fields) ValidationSchemaWithDefaults() (schema.Fields, schema.Defaults, error) {
+	fields, defaults, err := s.ValidationSchema()
+	if err != nil {
+		return nil, nil, errgo.Mask(err)
+	}
+	for name, attr := range s {
+		if attr.Default == nil {
+			continue
+		}
+		dv, err := fields[name].Coerce(attr.Default, nil)
+		if err != nil {
+			return nil, nil, errgo.Notef(err, "%s: invalid default value", name)
+		}
+		defaults[name] = dv
+	}
+	return fields, defaults, nil
+}
+
+// Validate coerces the given value against the schema in a single
+// call, building the validation schema, applying any Default values
+// for attributes missing from value, and returning the coerced map.
+// Null sentinels are pruned first (see PruneNull); an attribute that
+// has been explicitly unset that way is omitted from the result
+// without its Default applied, unlike an attribute that is simply
+// absent. An invalid schema is reported as a "cannot build
+// validation schema" error, distinct from coercion errors on the
+// value itself.
+func (s Fields) Validate(value map[string]interface{}) (map[string]interface{}, error) {
+	fields, defaults, err := s.ValidationSchema()
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot build validation schema")
+	}
+	v, err := schema.FieldMap(fields, defaults).Coerce(s.PruneNull(value), nil)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	result := v.(map[string]interface{})
+	for name, attr := range s {
+		if _, ok := result[name]; ok || attr.Default == nil {
+			continue
+		}
+		if _, ok := value[name]; ok {
+			// The attribute was explicitly unset with a null
+			// sentinel, so its default does not apply.
+			continue
+		}
+		dv, err := fields[name].Coerce(attr.Default, []string{name})
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid default value for %s", name)
+		}
+		result[name] = dv
+	}
+	return result, nil
+}
+
+// SetDefaults returns a copy of the given value map with every
+// absent non-mandatory attribute that has a Default set to its
+// coerced default value. Existing entries are left untouched. It
+// complements the schema.Omit behaviour of ValidationSchema for
+// callers that do want defaults applied. Attributes whose default
+// cannot be coerced are left unset.
+func (s Fields) SetDefaults(value map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(value))
+	for name, v := range value {
+		result[name] = v
+	}
+	for name, attr := range s {
+		if _, ok := result[name]; ok || attr.Mandatory || attr.Default == nil {
+			continue
+		}
+		checker, err := attr.Checker()
+		if err != nil {
+			continue
+		}
+		if dv, err := checker.Coerce(attr.Default, nil); err == nil {
+			result[name] = dv
+		}
+	}
+	return result
+}
+
+// ValidateConditional checks the conditional-mandatory constraints
+// declared with Attr.MandatoryIf against the given values: for each
+// attribute naming another in MandatoryIf, if the named attribute
+// has a non-empty value then the dependent attribute must have a
+// value too. It returns an error naming the first unsatisfied
+// dependency in attribute name order, or an error if the MandatoryIf
+// references form a cycle or name an unknown attribute.
+func (s Fields) ValidateConditional(values map[string]interface{}) error {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ref := s[name].MandatoryIf
+		if ref == "" {
+			continue
+		}
+		if _, ok := s[ref]; !ok {
+			return errgo.Newf("attribute %q is mandatory-if unknown attribute %q", name, ref)
+		}
+		// Follow the chain of references to detect cycles;
+		// each attribute has at most one, so a walk suffices.
+		seen := map[string]bool{name: true}
+		for r := ref; r != ""; r = s[r].MandatoryIf {
+			if seen[r] {
+				return errgo.Newf("cyclic mandatory-if reference involving %q", name)
+			}
+			seen[r] = true
+		}
+		v, ok := values[ref]
+		if !ok || isNullSentinel(v) || v == "" {
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			return errgo.Newf("attribute %q is mandatory when %q is set", name, ref)
+		}
+	}
+	return nil
+}
+
+// ValidateExclusive checks that exactly one member of each of the
+// given exclusive sets of attribute names is present in values, so
+// that alternatives such as token-based versus password-based
+// credentials can be modelled. It returns an error naming the
+// conflicting attributes when more than one member of a set is
+// present, or listing the set when none is.
+func (s Fields) ValidateExclusive(values map[string]interface{}, groups ...[]string) error {
+	for _, group := range groups {
+		var present []string
+		for _, name := range group {
+			if v, ok := values[name]; ok && !isNullSentinel(v) && v != "" {
+				present = append(present, name)
+			}
+		}
+		switch {
+		case len(present) > 1:
+			return errgo.Newf("attributes %s are mutually exclusive", strings.Join(present, ", "))
+		case len(present) == 0:
+			return errgo.Newf("exactly one of %s must be set", strings.Join(group, ", "))
+		}
+	}
+	return nil
+}
+
+// FilterGroup returns a new schema holding only the attributes
+// of s whose Group is g, so that a targeted form (for example an
+// account-only one) can be driven from a single master schema.
+func (s Fields) FilterGroup(g Group) Fields {
+	return s.FilterGroups(g)
+}
+
+// FilterGroups returns a new schema holding only the attributes
+// of s whose Group is one of the given groups.
+func (s Fields) FilterGroups(groups ...Group) Fields {
+	result := make(Fields)
+	for name, attr := range s {
+		for _, g := range groups {
+			if attr.Group == g {
+				result[name] = attr
+				break
+			}
+		}
+	}
+	return result
+}
+
+// ValidateAll is like Validate except that every problem with the
+// given value is reported rather than just the first: each attribute
+// is coerced independently and all coercion errors, along with an
+// error for each unknown attribute and each missing mandatory
+// attribute, are accumulated. The successfully coerced attributes
+// are returned regardless of errors, so a caller can report every
+// mistake in a config file at once. Coercion errors are ordered by
+// attribute name, followed by any missing-mandatory errors; the
+// error slice is nil if the value is valid. As for Validate, null
+// sentinels are pruned before coercion (see PruneNull).
+func (s Fields) ValidateAll(value map[string]interface{}) (map[string]interface{}, []error) {
+	value = s.PruneNull(value)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make(map[string]interface{}, len(value))
+	var errs []error
+	for _, name := range names {
+		attr, ok := s[name]
+		if !ok {
+			errs = append(errs, errgo.Newf("%s: unknown attribute", name))
+			continue
+		}
+		checker, err := attr.Checker()
+		if err != nil {
+			errs = append(errs, errgo.Notef(err, "%s", name))
+			continue
+		}
+		v, err := checker.Coerce(value[name], []string{name})
+		if err != nil {
+			errs = append(errs, errgo.Mask(err))
+			continue
+		}
+		result[name] = v
+	}
+	for _, name := range s.MissingMandatory(result) {
+		errs = append(errs, errgo.Newf("%s: missing mandatory attribute", name))
+	}
+	return result, errs
+}
+
+// AllEnvVars returns the names of all the environment variables
+// consulted by any attribute in s, deduplicated and sorted. It can
+// be used to document or pre-flight check the environment read
+// when filling in defaults for the schema.
+func (s Fields) AllEnvVars() []string {
+	seen := make(map[string]bool)
+	var vars []string
+	add := func(v string) {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			vars = append(vars, v)
+		}
+	}
+	for _, attr := range s {
+		add(attr.EnvVar)
+		for _, v := range attr.EnvVars {
+			add(v)
+		}
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Effective returns the effective configuration that would result
+// from applying the given attribute values to the schema. Each value
+// is coerced to its attribute's canonical type; missing attributes
+// are filled in first from the environment variables consulted by
+// the attribute and then from the attribute's Default, so explicit
+// values take precedence over environment defaults, which take
+// precedence over schema defaults. It returns an error if a value
+// is provided for an attribute not in the schema, if any value
+// cannot be coerced, or if a mandatory attribute remains unfilled.
+func (s Fields) Effective(values map[string]interface{}) (map[string]interface{}, error) {
+	for name := range values {
+		if _, ok := s[name]; !ok {
+			return nil, errgo.Newf("unknown attribute %q", name)
+		}
+	}
+	result := make(map[string]interface{}, len(s))
+	for name, attr := range s {
+		checker, err := attr.Checker()
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid attribute %s", name)
+		}
+		var val interface{}
+		if v, ok := values[name]; ok {
+			if attr.TreatNullAsUnset && isNullSentinel(v) {
+				// The attribute has been explicitly unset,
+				// so no defaults apply.
+				continue
+			}
+			val = v
+		} else if _, v := attr.defaultFromEnv(); v != "" {
+			val = v
+		} else if attr.Default != nil {
+			val = attr.Default
+		} else {
+			if attr.Mandatory {
+				return nil, errgo.Newf("no value for mandatory attribute %q", name)
+			}
+			continue
+		}
+		cv, err := checker.Coerce(val, []string{name})
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		result[name] = cv
+	}
+	return result, nil
+}
+
+// defaultFromEnv returns the name and value of the first
+// environment variable mentioned by attr that is set to a
+// non-empty value.
+func (attr Attr) defaultFromEnv() (envVar, val string) {
+	if attr.EnvVar != "" {
+		if val = os.Getenv(attr.EnvVar); val != "" {
+			return attr.EnvVar, val
+		}
+	}
+	for _, envVar := range attr.EnvVars {
+		if val = os.Getenv(envVar); val != "" {
+			return envVar, val
+		}
+	}
+	return "", ""
+}
+
+// SetEnvVars returns, for each environment variable consulted by an
+// attribute in s that is currently set to a non-empty value, the
+// variable name and its value. Values of variables consulted by
+// secret attributes are redacted. It is intended for pre-flight
+// diagnostics showing what the environment will supply.
+func (s Fields) SetEnvVars() map[string]string {
+	result := make(map[string]string)
+	for _, attr := range s {
+		vars := make([]string, 0, len(attr.EnvVars)+1)
+		if attr.EnvVar != "" {
+			vars = append(vars, attr.EnvVar)
+		}
+		vars = append(vars, attr.EnvVars...)
+		for _, v := range vars {
+			val := os.Getenv(v)
+			if val == "" {
+				continue
+			}
+			if attr.Secret {
+				val = "*****"
+			}
+			result[v] = val
+		}
+	}
+	return result
+}
+
+// PruneNull returns a copy of values with an entry removed
+// wherever its attribute has TreatNullAsUnset set and the value is
+// one of the recognized unset sentinels. It is applied by the
+// high-level coercion entry points - CoerceJSON, Validate and
+// ValidateAll; clients coercing with a validation schema directly
+// can call it themselves first.
+func (s Fields) PruneNull(values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		if attr, ok := s[name]; ok && attr.TreatNullAsUnset && isNullSentinel(v) {
+			continue
+		}
+		result[name] = v
+	}
+	return result
+}
+
+// isNullSentinel reports whether v is one of the values
+// recognized as meaning "explicitly unset".
+func isNullSentinel(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && (s == "null" || s == "none")
+}
+
+// PublicSchema returns a copy of s suitable for exposing to
+// untrusted clients: secret attributes remain present, so that
+// clients know they are expected, but their Example and Default
+// values are cleared to avoid leaking sensitive metadata.
+// Non-secret attributes are unchanged.
+func (s Fields) PublicSchema() Fields {
+	public := make(Fields, len(s))
+	for name, attr := range s {
+		if attr.Secret {
+			attr.Example = nil
+			attr.Default = nil
+		}
+		public[name] = attr
+	}
+	return public
+}
+
+// MissingMandatory returns the names of all mandatory attributes
+// in s that have no entry in values, sorted alphabetically.
+func (s Fields) MissingMandatory(values map[string]interface{}) []string {
+	var missing []string
+	for name, attr := range s {
+		if !attr.Mandatory {
+			continue
+		}
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// EnvVarCollisions returns a map from environment variable name to
+// the sorted names of the attributes that consult it, holding an
+// entry for each variable consulted by more than one attribute.
+// Such a variable can cause one attribute's default to shadow
+// another's, which is usually a schema mistake.
+func (s Fields) EnvVarCollisions() map[string][]string {
+	users := make(map[string][]string)
+	for name, attr := range s {
+		if attr.EnvVar != "" {
+			users[attr.EnvVar] = append(users[attr.EnvVar], name)
+		}
+		for _, v := range attr.EnvVars {
+			users[v] = append(users[v], name)
+		}
+	}
+	collisions := make(map[string][]string)
+	for v, names := range users {
+		if len(names) > 1 {
+			sort.Strings(names)
+			collisions[v] = names
+		}
+	}
+	return collisions
+}
+
+// ExampleYAML returns the fields formatted as a YAML
+// example, with non-mandatory fields commented out,
+// like the providers do currently.
+//
+// Each attribute is preceded by its description as a comment
+// and given a value derived from its Example, falling back to a
+// placeholder appropriate to its type. Secret attributes show a
+// masked placeholder rather than their real example. Attributes
+// are ordered by group then name for stable output.
+func (s Fields) ExampleYAML() []byte {
+	ordered := make(attrsByGroup, 0, len(s))
+	for name, attr := range s {
+		ordered = append(ordered, attrWithName{
+			name: name,
+			Attr: attr,
+		})
+	}
+	sort.Sort(ordered)
+	var buf bytes.Buffer
+	for i, f := range ordered {
+		if i > 0 {
+			buf.Write(nl)
+		}
+		writeSampleDescription(&buf, f.Attr, "# ")
+		var val interface{}
+		switch {
+		case f.Secret:
+			val = "*****"
+		case f.Example != nil:
+			val = f.Example
+		default:
+			val = sampleValue(f.Type)
+		}
+		if f.Mandatory {
+			fmt.Fprintf(&buf, "%s:", f.name)
+			indentVal(&buf, val, "")
+		} else {
+			fmt.Fprintf(&buf, "# %s:", f.name)
+			indentVal(&buf, val, "# ")
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,886 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/juju/schema"
+)
+
+func TestAllEnvVars(t *testing.T) {
+	fields := Fields{
+		"a": {Type: Tstring, EnvVar: "A_VAR", EnvVars: []string{"A_ALT", "SHARED_VAR"}},
+		"b": {Type: Tstring, EnvVar: "SHARED_VAR"},
+		"c": {Type: Tstring},
+	}
+	got := fields.AllEnvVars()
+	want := []string{"A_ALT", "A_VAR", "SHARED_VAR"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestAllEnvVarsNone(t *testing.T) {
+	fields := Fields{
+		"a": {Type: Tstring},
+	}
+	if got := fields.AllEnvVars(); len(got) != 0 {
+		t.Errorf("got %#v, want no variables", got)
+	}
+}
+
+func TestPresenceBool(t *testing.T) {
+	c := PresenceBool()
+	tests := []struct {
+		value interface{}
+		want  bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+		{true, true},
+		{false, false},
+	}
+	for _, test := range tests {
+		v, err := c.Coerce(test.value, nil)
+		if err != nil {
+			t.Errorf("Coerce(%#v): unexpected error: %v", test.value, err)
+			continue
+		}
+		if v != test.want {
+			t.Errorf("Coerce(%#v): got %#v, want %#v", test.value, v, test.want)
+		}
+	}
+	if _, err := c.Coerce("wibble", nil); err == nil {
+		t.Errorf("expected error for non-boolean input")
+	}
+}
+
+func TestTboolRejectsEmptyString(t *testing.T) {
+	// The presence semantics are strictly opt-in: the standard
+	// Tbool checker still rejects an empty string.
+	if _, err := checkers[Tbool].Coerce("", nil); err == nil {
+		t.Errorf("expected error coercing empty string with the standard Tbool checker")
+	}
+}
+
+func TestMultiValues(t *testing.T) {
+	attr := Attr{
+		Type:   Tstring,
+		Values: []interface{}{"a", "b", "c"},
+		Multi:  true,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("a, c", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+	if _, err := checker.Coerce("a,d", nil); err == nil {
+		t.Errorf("expected error for element outside the allowed values")
+	}
+	if _, err := checker.Coerce("a,a", nil); err == nil {
+		t.Errorf("expected error for duplicate element")
+	}
+}
+
+func TestSingleValueEnumUnaffectedByMulti(t *testing.T) {
+	attr := Attr{
+		Type:   Tstring,
+		Values: []interface{}{"a", "b"},
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("a", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "a" {
+		t.Errorf("got %#v, want %#v", v, "a")
+	}
+	if _, err := checker.Coerce("a,b", nil); err == nil {
+		t.Errorf("expected error coercing a comma list without Multi")
+	}
+}
+
+func TestMultiWithoutValues(t *testing.T) {
+	attr := Attr{
+		Type:  Tstring,
+		Multi: true,
+	}
+	if _, err := attr.Checker(); err == nil {
+		t.Errorf("expected error for multi without values")
+	}
+}
+
+func TestIsDefault(t *testing.T) {
+	attr := Attr{
+		Type:    Tint,
+		Default: 8080,
+	}
+	if !attr.IsDefault(8080) {
+		t.Errorf("matching value not reported as default")
+	}
+	// Both sides are coerced, so an equivalent string
+	// representation also matches.
+	if !attr.IsDefault("8080") {
+		t.Errorf("coercible matching value not reported as default")
+	}
+	if attr.IsDefault(80) {
+		t.Errorf("differing value reported as default")
+	}
+	if attr.IsDefault("not a number") {
+		t.Errorf("uncoercible value reported as default")
+	}
+}
+
+func TestIsDefaultNoDefault(t *testing.T) {
+	attr := Attr{
+		Type: Tint,
+	}
+	if attr.IsDefault(0) {
+		t.Errorf("value reported as default for attribute with no default")
+	}
+}
+
+func TestKeyPattern(t *testing.T) {
+	attr := Attr{
+		Type:       Tattrs,
+		KeyPattern: "^[a-z]+$",
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("foo=1 bar=2", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"foo": "1", "bar": "2"}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+	_, err = checker.Coerce("Foo=1", nil)
+	if err == nil {
+		t.Fatalf("expected error for non-conforming key")
+	}
+	if !strings.Contains(err.Error(), `invalid key "Foo"`) {
+		t.Errorf("error %q does not name the invalid key", err)
+	}
+}
+
+func TestKeyPatternInvalid(t *testing.T) {
+	attr := Attr{
+		Type:       Tattrs,
+		KeyPattern: "(",
+	}
+	if _, err := attr.Checker(); err == nil {
+		t.Errorf("expected error for invalid key pattern")
+	}
+}
+
+// setenv sets the given environment variable for the duration
+// of a test, returning a function that restores its old value.
+func setenv(name, value string) func() {
+	old, wasSet := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if wasSet {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestEffectivePrecedence(t *testing.T) {
+	fields := Fields{
+		"port": {
+			Type:    Tint,
+			EnvVar:  "TEST_EFFECTIVE_PORT",
+			Default: 80,
+		},
+	}
+	defer setenv("TEST_EFFECTIVE_PORT", "85")()
+
+	// An explicit value takes precedence over the environment.
+	result, err := fields.Effective(map[string]interface{}{"port": 90})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["port"] != 90 {
+		t.Errorf("explicit value: got %#v, want 90", result["port"])
+	}
+
+	// The environment takes precedence over the schema default.
+	result, err = fields.Effective(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["port"] != 85 {
+		t.Errorf("env value: got %#v, want 85", result["port"])
+	}
+
+	// With no explicit value and no environment variable,
+	// the schema default applies.
+	os.Unsetenv("TEST_EFFECTIVE_PORT")
+	result, err = fields.Effective(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["port"] != 80 {
+		t.Errorf("schema default: got %#v, want 80", result["port"])
+	}
+}
+
+func TestEffectiveUnknownAttribute(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	if _, err := fields.Effective(map[string]interface{}{"bad": 1}); err == nil {
+		t.Errorf("expected error for unknown attribute")
+	}
+}
+
+func TestEffectiveMissingMandatory(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint, Mandatory: true},
+	}
+	if _, err := fields.Effective(nil); err == nil {
+		t.Errorf("expected error for missing mandatory attribute")
+	}
+}
+
+func TestAllowFileRef(t *testing.T) {
+	f, err := ioutil.TempFile("", "attrs")
+	if err != nil {
+		t.Fatalf("cannot create temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("x: 1\ny: two\n"); err != nil {
+		t.Fatalf("cannot write temporary file: %v", err)
+	}
+	f.Close()
+
+	attr := Attr{
+		Type:         Tattrs,
+		AllowFileRef: true,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("@"+f.Name(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"x": "1", "y": "two"}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+	// A literal value is still accepted.
+	v, err = checker.Coerce("k=v", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(v, map[string]string{"k": "v"}) {
+		t.Errorf("got %#v, want %#v", v, map[string]string{"k": "v"})
+	}
+}
+
+func TestFileRefIgnoredWithoutFlag(t *testing.T) {
+	attr := Attr{
+		Type: Tattrs,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Without AllowFileRef an @-prefixed string is parsed as
+	// ordinary key=value input, which fails.
+	if _, err := checker.Coerce("@somefile", nil); err == nil {
+		t.Errorf("expected error coercing @-prefixed value without AllowFileRef")
+	}
+}
+
+func TestStep(t *testing.T) {
+	attr := Attr{
+		Type: Tint,
+		Step: 5,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce(10, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 10 {
+		t.Errorf("got %#v, want 10", v)
+	}
+	_, err = checker.Coerce(7, nil)
+	if err == nil {
+		t.Fatalf("expected error for non-multiple")
+	}
+	if !strings.Contains(err.Error(), "value must be a multiple of 5") {
+		t.Errorf("error %q does not mention the step", err)
+	}
+}
+
+func TestStepWithRange(t *testing.T) {
+	min, max := 10, 30
+	attr := Attr{
+		Type: Tint,
+		Step: 5,
+		Min:  &min,
+		Max:  &max,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := checker.Coerce(20, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := checker.Coerce(35, nil); err == nil {
+		t.Errorf("expected error for value above the maximum")
+	}
+	if _, err := checker.Coerce(12, nil); err == nil {
+		t.Errorf("expected error for non-multiple within the range")
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	defer setenv("TEST_EXPAND_VAR", "world")()
+	attr := Attr{
+		Type:      Tstring,
+		ExpandEnv: true,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("hello-$TEST_EXPAND_VAR", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "hello-world" {
+		t.Errorf("got %#v, want %q", v, "hello-world")
+	}
+	// An unset variable expands to the empty string.
+	os.Unsetenv("TEST_EXPAND_VAR")
+	v, err = checker.Coerce("hello-$TEST_EXPAND_VAR", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "hello-" {
+		t.Errorf("got %#v, want %q", v, "hello-")
+	}
+}
+
+func TestNoExpandEnvWithoutFlag(t *testing.T) {
+	defer setenv("TEST_EXPAND_VAR", "world")()
+	attr := Attr{
+		Type: Tstring,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("hello-$TEST_EXPAND_VAR", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "hello-$TEST_EXPAND_VAR" {
+		t.Errorf("got %#v, want the literal value", v)
+	}
+}
+
+func TestExampleInError(t *testing.T) {
+	attr := Attr{
+		Type:           Tint,
+		Example:        8080,
+		ExampleInError: true,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = checker.Coerce("not a number", nil)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "(example: 8080)") {
+		t.Errorf("error %q does not include the example", err)
+	}
+}
+
+func TestExampleNotInErrorWithoutFlag(t *testing.T) {
+	attr := Attr{
+		Type:    Tint,
+		Example: 8080,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = checker.Coerce("not a number", nil)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if strings.Contains(err.Error(), "example") {
+		t.Errorf("error %q unexpectedly includes the example", err)
+	}
+}
+
+func TestSetEnvVars(t *testing.T) {
+	defer setenv("TEST_SET_REGION", "us-east-1")()
+	defer setenv("TEST_SET_TOKEN", "supersecret")()
+	fields := Fields{
+		"region": {Type: Tstring, EnvVar: "TEST_SET_REGION"},
+		"token":  {Type: Tstring, EnvVar: "TEST_SET_TOKEN", Secret: true},
+		"other":  {Type: Tstring, EnvVar: "TEST_SET_UNSET_VAR"},
+	}
+	os.Unsetenv("TEST_SET_UNSET_VAR")
+	got := fields.SetEnvVars()
+	want := map[string]string{
+		"TEST_SET_REGION": "us-east-1",
+		// Values of variables consulted by secret
+		// attributes are redacted.
+		"TEST_SET_TOKEN": "*****",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestDurationUnit(t *testing.T) {
+	attr := Attr{
+		Type:         Tduration,
+		DurationUnit: "ms",
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("1500ms", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 1500 {
+		t.Errorf("got %#v, want 1500", v)
+	}
+
+	attr.DurationUnit = "s"
+	checker, err = attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A duration that is not a whole number of the unit
+	// is an error rather than being rounded.
+	_, err = checker.Coerce("1500ms", nil)
+	if err == nil {
+		t.Fatalf("expected error for non-integral number of seconds")
+	}
+	if !strings.Contains(err.Error(), "not a whole number of s") {
+		t.Errorf("error %q does not mention the unit", err)
+	}
+	v, err = checker.Coerce("2s", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 2 {
+		t.Errorf("got %#v, want 2", v)
+	}
+}
+
+func TestDurationUnitInvalid(t *testing.T) {
+	attr := Attr{
+		Type:         Tduration,
+		DurationUnit: "fortnights",
+	}
+	if _, err := attr.Checker(); err == nil {
+		t.Errorf("expected error for unknown duration unit")
+	}
+	attr = Attr{
+		Type:         Tstring,
+		DurationUnit: "s",
+	}
+	if _, err := attr.Checker(); err == nil {
+		t.Errorf("expected error for duration unit on non-duration type")
+	}
+}
+
+func TestPublicSchema(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, Example: "us-east-1", Default: "eu-west-1"},
+		"token":  {Type: Tstring, Secret: true, Example: "tok-123", Default: "tok-456"},
+	}
+	public := fields.PublicSchema()
+	// The secret attribute retains its declaration but drops
+	// its sensitive metadata.
+	token, ok := public["token"]
+	if !ok {
+		t.Fatalf("secret attribute missing from public schema")
+	}
+	if token.Example != nil || token.Default != nil {
+		t.Errorf("secret attribute retains sensitive metadata: %#v", token)
+	}
+	if !token.Secret {
+		t.Errorf("secret attribute no longer marked secret")
+	}
+	// Non-secret attributes are unchanged.
+	if !reflect.DeepEqual(public["region"], fields["region"]) {
+		t.Errorf("non-secret attribute changed: %#v", public["region"])
+	}
+	// The original schema is not modified.
+	if fields["token"].Example == nil || fields["token"].Default == nil {
+		t.Errorf("original schema modified: %#v", fields["token"])
+	}
+}
+
+func TestValueSeparator(t *testing.T) {
+	attr := Attr{Type: Tattrs, ValueSeparator: ","}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("a=1,2 b=3", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]string{
+		"a": {"1", "2"},
+		// A value without the separator becomes a
+		// one-element slice.
+		"b": {"3"},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+}
+
+func TestNoValueSeparator(t *testing.T) {
+	attr := Attr{Type: Tattrs}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("a=1,2 b=3", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Without a separator the canonical form remains
+	// map[string]string.
+	want := map[string]string{
+		"a": "1,2",
+		"b": "3",
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+}
+
+// upperC is a stricter Tstring checker used to test checker
+// overrides: it upper-cases every coerced string.
+type upperC struct{}
+
+func (upperC) Coerce(v interface{}, path []string) (interface{}, error) {
+	s, err := schema.String().Coerce(v, path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(s.(string)), nil
+}
+
+func TestValidationSchemaWithCheckers(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+	}
+	sfields, defaults, err := fields.ValidationSchemaWithCheckers(map[FieldType]schema.Checker{
+		Tstring: upperC{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := schema.FieldMap(sfields, defaults).Coerce(map[string]interface{}{
+		"region": "us-east-1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := v.(map[string]interface{})
+	if m["region"] != "US-EAST-1" {
+		t.Errorf("got %#v, want the overridden checker's result", m["region"])
+	}
+}
+
+func TestValidationSchemaWithCheckersNilOverrides(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+	}
+	sfields, defaults, err := fields.ValidationSchemaWithCheckers(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := schema.FieldMap(sfields, defaults).Coerce(map[string]interface{}{
+		"region": "us-east-1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := v.(map[string]interface{})
+	if m["region"] != "us-east-1" {
+		t.Errorf("got %#v, want the default checker's result", m["region"])
+	}
+}
+
+func TestValidatePrunesNullSentinels(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, TreatNullAsUnset: true},
+		"port":   {Type: Tint},
+	}
+	for _, sentinel := range []interface{}{nil, "null", "none"} {
+		result, err := fields.Validate(map[string]interface{}{
+			"region": sentinel,
+			"port":   80,
+		})
+		if err != nil {
+			t.Fatalf("sentinel %#v: unexpected error: %v", sentinel, err)
+		}
+		if _, ok := result["region"]; ok {
+			t.Errorf("sentinel %#v: explicitly unset attribute present in result", sentinel)
+		}
+		if result["port"] != 80 {
+			t.Errorf("sentinel %#v: got port %#v, want 80", sentinel, result["port"])
+		}
+	}
+}
+
+func TestValidateNullRetainedWithoutFlag(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+	}
+	result, err := fields.Validate(map[string]interface{}{
+		"region": "null",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Without TreatNullAsUnset the sentinel is an ordinary string.
+	if result["region"] != "null" {
+		t.Errorf("got %#v, want the literal string", result["region"])
+	}
+}
+
+func TestValidateExplicitUnsetSuppressesDefault(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, TreatNullAsUnset: true, Default: "eu-west-1"},
+	}
+	result, err := fields.Validate(map[string]interface{}{
+		"region": "null",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := result["region"]; ok {
+		t.Errorf("explicitly unset attribute got a default: %#v", result["region"])
+	}
+	// An absent attribute still receives the default.
+	result, err = fields.Validate(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["region"] != "eu-west-1" {
+		t.Errorf("got %#v, want the default value", result["region"])
+	}
+}
+
+func TestValidateAllPrunesNullSentinels(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, TreatNullAsUnset: true},
+	}
+	result, errs := fields.ValidateAll(map[string]interface{}{
+		"region": nil,
+	})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if _, ok := result["region"]; ok {
+		t.Errorf("explicitly unset attribute present in result")
+	}
+}
+
+func TestTpath(t *testing.T) {
+	defer func(old func(string) ([]byte, error)) {
+		readFile = old
+	}(readFile)
+	readFile = func(name string) ([]byte, error) {
+		if name != "/etc/motd" {
+			t.Errorf("read unexpected file %q", name)
+		}
+		return []byte("hello world\n"), nil
+	}
+	checker, err := Attr{Type: Tpath}.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce("/etc/motd", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "hello world\n" {
+		t.Errorf("got %#v, want the file contents", v)
+	}
+}
+
+func TestTpathReadError(t *testing.T) {
+	defer func(old func(string) ([]byte, error)) {
+		readFile = old
+	}(readFile)
+	readFile = func(name string) ([]byte, error) {
+		return nil, os.ErrNotExist
+	}
+	checker, err := Attr{Type: Tpath}.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = checker.Coerce("/nonexistent", nil)
+	if err == nil {
+		t.Fatalf("expected error for unreadable file")
+	}
+	if !strings.Contains(err.Error(), `cannot read file "/nonexistent"`) {
+		t.Errorf("error %q does not name the unreadable file", err)
+	}
+}
+
+func TestValidationSchemaWithDefaults(t *testing.T) {
+	fields := Fields{
+		"port":   {Type: Tint, Default: "8080"},
+		"region": {Type: Tstring},
+	}
+	sfields, defaults, err := fields.ValidationSchemaWithDefaults()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := schema.FieldMap(sfields, defaults).Coerce(map[string]interface{}{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := v.(map[string]interface{})
+	// The default is coerced to the attribute's canonical type.
+	if m["port"] != 8080 {
+		t.Errorf("got %#v, want the coerced default", m["port"])
+	}
+	// An attribute with no default is still omitted.
+	if _, ok := m["region"]; ok {
+		t.Errorf("attribute with no default present in result: %#v", m["region"])
+	}
+}
+
+func TestValidationSchemaWithDefaultsInvalidDefault(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint, Default: "not a number"},
+	}
+	_, _, err := fields.ValidationSchemaWithDefaults()
+	if err == nil {
+		t.Fatalf("expected error for invalid default")
+	}
+	if !strings.Contains(err.Error(), "port: invalid default value") {
+		t.Errorf("error %q does not report the invalid default", err)
+	}
+}
+
+func TestValidationSchemaInvalidExample(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint, Example: "not a number"},
+	}
+	_, _, err := fields.ValidationSchema()
+	if err == nil {
+		t.Fatalf("expected error for invalid example")
+	}
+	if !strings.Contains(err.Error(), "port: invalid example value") {
+		t.Errorf("error %q does not report the invalid example", err)
+	}
+}
+
+func TestValidationSchemaValidExample(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint, Example: "8080"},
+	}
+	if _, _, err := fields.ValidationSchema(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidationSchemaTpathExampleNotRead(t *testing.T) {
+	// A Tpath example names a file that need not exist where
+	// the schema is built, so it is not checked by coercion.
+	fields := Fields{
+		"cert": {Type: Tpath, Example: "/nonexistent/file"},
+	}
+	if _, _, err := fields.ValidationSchema(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTuintConstraints(t *testing.T) {
+	min, max := 1024, 65535
+	attr := Attr{
+		Type: Tuint,
+		Min:  &min,
+		Max:  &max,
+		Step: 2,
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, err := checker.Coerce(2048, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 2048 {
+		t.Errorf("got %#v, want 2048", v)
+	}
+	_, err = checker.Coerce(80, nil)
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("got error %v, want an out-of-range error", err)
+	}
+	_, err = checker.Coerce(2047, nil)
+	if err == nil || !strings.Contains(err.Error(), "must be a multiple of 2") {
+		t.Errorf("got error %v, want a step error", err)
+	}
+	// Negativity is reported by the Tuint checker itself,
+	// before the range is considered.
+	_, err = checker.Coerce(-4, nil)
+	if err == nil || !strings.Contains(err.Error(), "value must be non-negative") {
+		t.Errorf("got error %v, want a non-negative error", err)
+	}
+}
+
+func TestMinMaxOnNonIntegerType(t *testing.T) {
+	min := 1
+	attr := Attr{
+		Type: Tstring,
+		Min:  &min,
+	}
+	_, err := attr.Checker()
+	if err == nil || !strings.Contains(err.Error(), `min/max specified on non-integer type "string"`) {
+		t.Errorf("got error %v, want a non-integer type error", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Theme holds the ANSI escape sequences used to colorize prompt
+// output. An empty sequence leaves the corresponding text unstyled.
+type Theme struct {
+	// Label is used for field labels.
+	Label string
+
+	// Error is used for error messages.
+	Error string
+
+	// Default is used when displaying default values.
+	Default string
+}
+
+// DefaultTheme holds the theme used when colorization is enabled
+// and no other theme has been specified: bold labels, red errors
+// and dim defaults.
+var DefaultTheme = Theme{
+	Label:   "\x1b[1m",
+	Error:   "\x1b[31m",
+	Default: "\x1b[2m",
+}
+
+// reset is written after any colorized text to restore
+// the terminal's default rendition.
+const reset = "\x1b[0m"
+
+// colorize returns s wrapped in the given escape sequence,
+// or s unchanged if the sequence is empty.
+func colorize(code, s string) string {
+	if code == "" {
+		return s
+	}
+	return code + s + reset
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
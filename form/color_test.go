@@ -0,0 +1,56 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+func TestColorize(t *testing.T) {
+	if got, want := colorize(DefaultTheme.Label, "name"), "\x1b[1mname\x1b[0m"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := colorize("", "name"), "name"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPromptPlainOutputWhenColorDisabled(t *testing.T) {
+	var out bytes.Buffer
+	p := IOPrompter{
+		In:  strings.NewReader("value\n"),
+		Out: &out,
+	}
+	resp, err := p.Prompt("name", environschema.Attr{Type: environschema.Tstring})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "value" {
+		t.Errorf("got response %q, want %q", resp, "value")
+	}
+	if strings.Contains(out.String(), "\x1b") {
+		t.Errorf("output %q contains escape sequences", out.String())
+	}
+}
+
+func TestPromptNoColorOnNonTerminal(t *testing.T) {
+	// Even with Color enabled, output to a non-terminal
+	// must never contain escape sequences.
+	var out bytes.Buffer
+	p := IOPrompter{
+		In:    strings.NewReader("value\n"),
+		Out:   &out,
+		Color: true,
+	}
+	if _, err := p.Prompt("name", environschema.Attr{Type: environschema.Tstring}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "\x1b") {
+		t.Errorf("output %q contains escape sequences", out.String())
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"github.com/juju/schema"
+	"gopkg.in/errgo.v1"
+)
+
+// DefaultSource is a single layer consulted by a DefaultResolver
+// when resolving the default value for an attribute.
+type DefaultSource struct {
+	// Name identifies the source when attributing
+	// a resolved default.
+	Name string
+
+	// Get returns the default value for the given attribute.
+	// It has the same contract as IOFiller.GetDefault.
+	Get func(attr NamedAttr, checker schema.Checker) (val interface{}, display string, err error)
+}
+
+// DefaultResolver resolves default values by consulting an ordered
+// list of sources, so that the usual precedence logic (for example
+// explicit values, then environment variables, then schema
+// defaults) lives in one place rather than being duplicated
+// across fillers.
+type DefaultResolver struct {
+	// Sources holds the sources to consult, in
+	// decreasing order of precedence.
+	Sources []DefaultSource
+}
+
+// Resolve returns the default value for the given attribute from
+// the first source that provides one, along with the name of that
+// source. If no source provides a value it returns (nil, "", "",
+// nil).
+func (r DefaultResolver) Resolve(attr NamedAttr, checker schema.Checker) (val interface{}, display, source string, err error) {
+	for _, src := range r.Sources {
+		val, display, err := src.Get(attr, checker)
+		if err != nil {
+			return nil, "", "", errgo.Notef(err, "cannot get default from %s", src.Name)
+		}
+		if val != nil {
+			return val, display, src.Name, nil
+		}
+	}
+	return nil, "", "", nil
+}
+
+// GetDefault returns the resolved default without source
+// attribution, making a DefaultResolver usable directly as an
+// IOFiller.GetDefault function.
+func (r DefaultResolver) GetDefault(attr NamedAttr, checker schema.Checker) (interface{}, string, error) {
+	val, display, _, err := r.Resolve(attr, checker)
+	if err != nil {
+		return nil, "", errgo.Mask(err)
+	}
+	return val, display, nil
+}
+
+// ValuesSource returns a source with the given name that resolves
+// defaults from the given value map. The values are coerced when
+// resolved.
+func ValuesSource(name string, values map[string]interface{}) DefaultSource {
+	return DefaultSource{
+		Name: name,
+		Get: func(attr NamedAttr, checker schema.Checker) (interface{}, string, error) {
+			v, ok := values[attr.Name]
+			if !ok {
+				return nil, "", nil
+			}
+			cv, err := checker.Coerce(v, nil)
+			if err != nil {
+				return nil, "", errgo.Mask(err)
+			}
+			return cv, "", nil
+		},
+	}
+}
+
+// EnvSource returns a source that resolves defaults from the
+// environment variables declared by the attribute, as
+// DefaultFromEnv does.
+func EnvSource() DefaultSource {
+	return DefaultSource{
+		Name: "environment",
+		Get:  DefaultFromEnv,
+	}
+}
+
+// SchemaSource returns a source that resolves defaults from the
+// attribute's own Default value.
+func SchemaSource() DefaultSource {
+	return DefaultSource{
+		Name: "schema",
+		Get: func(attr NamedAttr, checker schema.Checker) (interface{}, string, error) {
+			if attr.Default == nil {
+				return nil, "", nil
+			}
+			v, err := checker.Coerce(attr.Default, nil)
+			if err != nil {
+				return nil, "", errgo.Mask(err)
+			}
+			return v, "", nil
+		},
+	}
+}
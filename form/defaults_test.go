@@ -0,0 +1,95 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+func TestDefaultResolverPrecedence(t *testing.T) {
+	attr := NamedAttr{
+		Name: "port",
+		Attr: environschema.Attr{
+			Type:    environschema.Tint,
+			Default: 80,
+		},
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	explicit := map[string]interface{}{"port": 90}
+	saved := map[string]interface{}{"port": 85}
+	tests := []struct {
+		about      string
+		sources    []DefaultSource
+		wantVal    interface{}
+		wantSource string
+	}{{
+		about: "explicit value wins",
+		sources: []DefaultSource{
+			ValuesSource("explicit", explicit),
+			ValuesSource("saved", saved),
+			SchemaSource(),
+		},
+		wantVal:    90,
+		wantSource: "explicit",
+	}, {
+		about: "saved value when no explicit value",
+		sources: []DefaultSource{
+			ValuesSource("explicit", nil),
+			ValuesSource("saved", saved),
+			SchemaSource(),
+		},
+		wantVal:    85,
+		wantSource: "saved",
+	}, {
+		about: "schema default as last resort",
+		sources: []DefaultSource{
+			ValuesSource("explicit", nil),
+			ValuesSource("saved", nil),
+			SchemaSource(),
+		},
+		wantVal:    80,
+		wantSource: "schema",
+	}}
+	for _, test := range tests {
+		r := DefaultResolver{Sources: test.sources}
+		val, _, source, err := r.Resolve(attr, checker)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.about, err)
+			continue
+		}
+		if val != test.wantVal {
+			t.Errorf("%s: got value %#v, want %#v", test.about, val, test.wantVal)
+		}
+		if source != test.wantSource {
+			t.Errorf("%s: got source %q, want %q", test.about, source, test.wantSource)
+		}
+	}
+}
+
+func TestDefaultResolverNoValue(t *testing.T) {
+	attr := NamedAttr{
+		Name: "port",
+		Attr: environschema.Attr{Type: environschema.Tint},
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := DefaultResolver{Sources: []DefaultSource{
+		ValuesSource("explicit", nil),
+		SchemaSource(),
+	}}
+	val, display, source, err := r.Resolve(attr, checker)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != nil || display != "" || source != "" {
+		t.Errorf("got (%#v, %q, %q), want no resolved default", val, display, source)
+	}
+}
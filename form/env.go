@@ -0,0 +1,43 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"strings"
+
+	"gopkg.in/errgo.v1"
+)
+
+// EnvFiller is a Filler that takes every value from the process
+// environment using each attribute's EnvVar and EnvVars, with no
+// prompting at all, for twelve-factor style configuration.
+type EnvFiller struct{}
+
+// Fill implements Filler.Fill by reading the value of each field
+// from the first of its environment variables that is set to a
+// non-empty value and coercing it to the field's type. Fields whose
+// variables are all unset are omitted from the result; an error is
+// returned naming any mandatory fields that remain unfilled.
+func (EnvFiller) Fill(form Form) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(form.Fields))
+	for _, field := range SortedFields(form.Fields) {
+		checker, err := field.Checker()
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid field %s", field.Name)
+		}
+		env, v := defaultFromEnv(field.Attr)
+		if env == "" {
+			continue
+		}
+		cv, err := checker.Coerce(v, nil)
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot convert $%s", env)
+		}
+		values[field.Name] = cv
+	}
+	if missing := form.Fields.MissingMandatory(values); len(missing) > 0 {
+		return nil, errgo.Newf("no environment variable set for mandatory fields: %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"flag"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// FlagFiller is a Filler that takes its values from command line
+// flags, so that the same schema can drive both interactive and
+// flag-based entry. RegisterFlags must be called before the flag
+// set is parsed; Fill then reads the parsed values.
+type FlagFiller struct {
+	// Set holds the flag set that flags are registered in.
+	// If it is nil, flag.CommandLine is used.
+	Set *flag.FlagSet
+
+	flags map[string]*flagValue
+}
+
+// RegisterFlags registers one flag per attribute in fields, named
+// after the attribute (see environschema.FlagName) and using its
+// description as usage text. Boolean attributes become boolean
+// flags that may be given without a value.
+func (f *FlagFiller) RegisterFlags(fields environschema.Fields) {
+	set := f.Set
+	if set == nil {
+		set = flag.CommandLine
+	}
+	if f.flags == nil {
+		f.flags = make(map[string]*flagValue)
+	}
+	for name, attr := range fields {
+		v := &flagValue{
+			isBool: attr.Type == environschema.Tbool,
+		}
+		set.Var(v, environschema.FlagName(name), attr.Description)
+		f.flags[name] = v
+	}
+}
+
+// Fill implements Filler.Fill by coercing the value of each flag
+// that was set on the command line to its attribute's type.
+// Attributes whose flag was not set fall back to any default from
+// the environment or the attribute itself (see DefaultFromEnv).
+func (f *FlagFiller) Fill(form Form) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(form.Fields))
+	for _, field := range SortedFields(form.Fields) {
+		checker, err := field.Checker()
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid field %s", field.Name)
+		}
+		fv := f.flags[field.Name]
+		if fv == nil || !fv.set {
+			def, _, err := DefaultFromEnv(field, checker)
+			if err != nil {
+				return nil, errgo.Mask(err)
+			}
+			if def != nil {
+				values[field.Name] = def
+			}
+			continue
+		}
+		v, err := checker.Coerce(fv.value, []string{field.Name})
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		values[field.Name] = v
+	}
+	if missing := form.Fields.MissingMandatory(values); len(missing) > 0 {
+		return nil, errgo.Newf("missing mandatory flags: %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// flagValue records the raw string given for a flag and whether
+// the flag was set at all, so that unset flags can fall back to
+// defaults rather than a zero value.
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+// String implements flag.Value.String.
+func (v *flagValue) String() string {
+	return v.value
+}
+
+// Set implements flag.Value.Set.
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	v.set = true
+	return nil
+}
+
+// IsBoolFlag reports whether the flag may be given without a
+// value, which the flag package treats as "true".
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
@@ -0,0 +1,548 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+// Package form provides ways to create and process forms based on
+// environschema schemas.
+//
+// The most common entry point for filling a form interactively
+// is IOFiller; PromptingFiller provides a lower-level alternative
+// for implementations that wish to control how each prompt
+// is presented.
+package form
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/juju/schema"
+	"golang.org/x/crypto/ssh/terminal"
+	"gopkg.in/errgo.v1"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// Form describes a form based on a schema.
+type Form struct {
+	// Title holds a title describing the form.
+	Title string
+
+	// Fields holds the fields included in the form.
+	Fields environschema.Fields
+}
+
+// MissingMandatoryForm returns a Form with the given title holding
+// only the mandatory attributes in fields that have no entry in
+// values, so that a "finish setup" flow can prompt for the minimum
+// necessary input.
+func MissingMandatoryForm(fields environschema.Fields, values map[string]interface{}, title string) Form {
+	missing := make(environschema.Fields)
+	for _, name := range fields.MissingMandatory(values) {
+		missing[name] = fields[name]
+	}
+	return Form{
+		Title:  title,
+		Fields: missing,
+	}
+}
+
+// Filler represents an object that can fill out a Form.
+type Filler interface {
+	// Fill returns a value for each field in the form,
+	// with an entry for every field name in the form's
+	// schema that a value was obtained for.
+	Fill(f Form) (map[string]interface{}, error)
+}
+
+// IOFiller is a Filler based around an io.Reader and io.Writer.
+type IOFiller struct {
+	// In is used to read responses from the user. If this is nil,
+	// then os.Stdin will be used.
+	In io.Reader
+
+	// Out is used to write prompts and information to the user. If
+	// this is nil, then os.Stdout will be used.
+	Out io.Writer
+
+	// MaxTries is the number of times to attempt to get a valid
+	// response when prompting. If this is 0 then the default of 3
+	// attempts will be used.
+	MaxTries int
+
+	// ShowDescriptions holds whether attribute descriptions
+	// should be printed as well as the attribute names.
+	ShowDescriptions bool
+
+	// ShowRetriesLeft holds whether the message printed after
+	// invalid input should include the number of attempts
+	// remaining before the fill is abandoned.
+	ShowRetriesLeft bool
+
+	// ShowSecretStrength holds whether a strength indicator
+	// (see EvalStrength) is printed after a value has been
+	// entered for a secret attribute. The indicator never
+	// includes the value itself.
+	ShowSecretStrength bool
+
+	// Hints maps field types to the hint printed after input
+	// that fails coercion, to guide the retry. If it is nil,
+	// DefaultHints will be used; a type with no entry gets
+	// no hint.
+	Hints map[environschema.FieldType]string
+
+	// Quiet holds whether prompt text is suppressed when In is
+	// not a terminal, so that piping responses to the filler
+	// does not clutter the output with prompts. Titles,
+	// warnings and error messages are still written.
+	Quiet bool
+
+	// WarnShadowedVars holds whether a warning should be
+	// printed before filling when an environment variable is
+	// consulted by more than one attribute in the form, so
+	// that it is clear why the same default value appears for
+	// several attributes.
+	WarnShadowedVars bool
+
+	// GetDefault returns the default value for the given attribute,
+	// which must have been coerced using the given checker.
+	// If there is no default, it should return (nil, "", nil).
+	//
+	// The display return value holds the string to use
+	// to describe the value of the default. If it's empty,
+	// fmt.Sprint(val) will be used.
+	//
+	// If GetDefault is nil, DefaultFromEnv will be used.
+	GetDefault func(attr NamedAttr, checker schema.Checker) (val interface{}, display string, err error)
+}
+
+// DefaultHints holds the hint printed for each field type after
+// input that fails coercion when no other hints have been
+// specified (see IOFiller.Hints).
+var DefaultHints = map[environschema.FieldType]string{
+	environschema.Tint:      "Please enter a whole number like 42.",
+	environschema.Tuint:     "Please enter a non-negative whole number.",
+	environschema.Tbool:     `Please enter "true" or "false".`,
+	environschema.Tattrs:    "Please enter space-separated key=value pairs.",
+	environschema.Tduration: `Please enter a duration with a unit like "30s" or "5m".`,
+	environschema.Tlist:     "Please enter comma-separated values.",
+	environschema.Tpath:     "Please enter the name of a readable file.",
+}
+
+// NamedAttr associates a name with an environschema.Attr.
+type NamedAttr struct {
+	Name string
+	environschema.Attr
+}
+
+// Fill implements Filler.Fill by writing the field information to
+// f.Out, then reading input from f.In. If f.In is a terminal and the
+// attribute is secret, echo will be disabled.
+//
+// Fill processes fields by first sorting them and then prompting for
+// the value of each one in turn.
+//
+// The fields are sorted by first by group name. Those in the same
+// group are sorted so that secret fields come after non-secret ones,
+// finally the fields are sorted by name.
+//
+// Each field will be prompted for, then the returned value will be
+// validated against the field's type. If the returned value does not
+// validate correctly it will be prompted again up to MaxTries before
+// giving up.
+func (f IOFiller) Fill(form Form) (map[string]interface{}, error) {
+	if f.MaxTries == 0 {
+		f.MaxTries = 3
+	}
+	if f.In == nil {
+		f.In = os.Stdin
+	}
+	if f.Out == nil {
+		f.Out = os.Stdout
+	}
+	if f.GetDefault == nil {
+		f.GetDefault = DefaultFromEnv
+	}
+	fields := SortedFields(form.Fields)
+	values := make(map[string]interface{}, len(fields))
+	if form.Title != "" {
+		if err := f.printf("%s\n", form.Title); err != nil {
+			return nil, errgo.Mask(err)
+		}
+	}
+	if f.WarnShadowedVars {
+		collisions := form.Fields.EnvVarCollisions()
+		vars := make([]string, 0, len(collisions))
+		for v := range collisions {
+			vars = append(vars, v)
+		}
+		sort.Strings(vars)
+		for _, v := range vars {
+			if err := f.printf("Warning: $%s provides a default for several attributes: %s.\n", v, strings.Join(collisions[v], ", ")); err != nil {
+				return nil, errgo.Mask(err)
+			}
+		}
+	}
+	reader := bufio.NewReader(f.In)
+	for _, field := range fields {
+		v, err := f.promptLoop(field, reader)
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot complete form")
+		}
+		if v != nil {
+			values[field.Name] = v
+		}
+	}
+	return values, nil
+}
+
+// printf writes the formatted output to f.Out.
+func (f IOFiller) printf(format string, a ...interface{}) error {
+	if _, err := fmt.Fprintf(f.Out, format, a...); err != nil {
+		return errgo.Notef(err, "cannot write output")
+	}
+	return nil
+}
+
+// promptLoop prompts for the value of the given field,
+// retrying up to f.MaxTries times if the value cannot
+// be coerced to the field's type.
+func (f IOFiller) promptLoop(attr NamedAttr, r *bufio.Reader) (interface{}, error) {
+	checker, err := attr.Checker()
+	if err != nil {
+		return nil, errgo.Notef(err, "invalid field %s", attr.Name)
+	}
+	def, defDisplay, err := f.GetDefault(attr, checker)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot get default value for %s", attr.Name)
+	}
+	if f.ShowDescriptions && attr.Description != "" {
+		desc := strings.TrimSpace(attr.Description)
+		if width, ok := outputWidth(f.Out); ok {
+			desc = wrapText(desc, width)
+		}
+		if err := f.printf("\n%s\n", desc); err != nil {
+			return nil, errgo.Mask(err)
+		}
+	}
+	if attr.Deprecated {
+		msg := fmt.Sprintf("warning: attribute %s is deprecated", attr.Name)
+		if attr.DeprecatedMessage != "" {
+			msg += ": " + attr.DeprecatedMessage
+		}
+		if err := f.printf("%s\n", msg); err != nil {
+			return nil, errgo.Mask(err)
+		}
+	}
+	for i := 0; i < f.MaxTries; i++ {
+		line, err := f.prompt(attr, r, def, defDisplay)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		if attr.Secret && line != "" && f.ShowSecretStrength {
+			if err := f.printf("strength: %s\n", EvalStrength(line)); err != nil {
+				return nil, errgo.Mask(err)
+			}
+		}
+		if line == "" {
+			// The user accepted the default value,
+			// which may be nil if the field is not
+			// mandatory.
+			if def != nil || !attr.Mandatory {
+				return def, nil
+			}
+			if err := f.printf("Cannot be empty.\n"); err != nil {
+				return nil, errgo.Mask(err)
+			}
+			continue
+		}
+		v, err := checker.Coerce(line, nil)
+		if err == nil {
+			return v, nil
+		}
+		msg := fmt.Sprintf("Invalid input: %v", err)
+		if left := f.MaxTries - i - 1; f.ShowRetriesLeft && left > 0 {
+			attempts := "attempts"
+			if left == 1 {
+				attempts = "attempt"
+			}
+			msg = fmt.Sprintf("%s (%d %s left)", msg, left, attempts)
+		}
+		if err := f.printf("%s\n", msg); err != nil {
+			return nil, errgo.Mask(err)
+		}
+		hints := f.Hints
+		if hints == nil {
+			hints = DefaultHints
+		}
+		if hint, ok := hints[attr.Type]; ok {
+			if err := f.printf("%s\n", hint); err != nil {
+				return nil, errgo.Mask(err)
+			}
+		}
+	}
+	return nil, errgo.New("too many invalid inputs")
+}
+
+// prompt writes a prompt for the given attribute and reads
+// the user's response. If the attribute has no default but has an
+// example value, the example is shown as a hint; unlike a default
+// it is never used as the value on empty input.
+func (f IOFiller) prompt(attr NamedAttr, r *bufio.Reader, def interface{}, defDisplay string) (string, error) {
+	prompt := attr.Name
+	if def != nil {
+		if defDisplay == "" {
+			if attr.Secret {
+				defDisplay = "*****"
+			} else {
+				defDisplay = fmt.Sprint(def)
+			}
+		}
+		prompt = fmt.Sprintf("%s [%s]", attr.Name, defDisplay)
+	} else if attr.Example != nil && !attr.Secret {
+		prompt = fmt.Sprintf("%s (e.g. %v)", attr.Name, attr.Example)
+	}
+	if !f.suppressPrompts() {
+		if err := f.printf("%s: ", prompt); err != nil {
+			return "", errgo.Mask(err)
+		}
+	}
+	line, err := f.readLine(attr.Secret, r)
+	if err != nil {
+		return "", errgo.Mask(err)
+	}
+	return strings.TrimRight(line, "\n"), nil
+}
+
+// outputWidth returns the width in columns of the terminal that w
+// writes to and whether a width could be determined at all. Output
+// that does not go to a terminal has no width, so text written to
+// it should not be wrapped.
+func outputWidth(w io.Writer) (int, bool) {
+	file, ok := w.(*os.File)
+	if !ok || !terminal.IsTerminal(int(file.Fd())) {
+		return 0, false
+	}
+	width, _, err := terminal.GetSize(int(file.Fd()))
+	if err != nil || width <= 0 {
+		return 80, true
+	}
+	return width, true
+}
+
+// wrapText greedily wraps the given text to the given width,
+// preserving existing line breaks.
+func wrapText(text string, width int) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		current := words[0]
+		for _, word := range words[1:] {
+			if len(current)+1+len(word) > width {
+				lines = append(lines, current)
+				current = word
+				continue
+			}
+			current += " " + word
+		}
+		lines = append(lines, current)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// suppressPrompts reports whether prompt text should be
+// suppressed because input is not interactive (see Quiet).
+func (f IOFiller) suppressPrompts() bool {
+	if !f.Quiet {
+		return false
+	}
+	file, ok := f.In.(*os.File)
+	return !ok || !terminal.IsTerminal(int(file.Fd()))
+}
+
+// readLine reads a single line of input. If secret is true
+// and the input is a terminal, echo will be disabled.
+func (f IOFiller) readLine(secret bool, r *bufio.Reader) (string, error) {
+	if secret {
+		if file, ok := f.In.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+			data, err := terminal.ReadPassword(int(file.Fd()))
+			if err != nil {
+				return "", errgo.Notef(err, "cannot read secret input")
+			}
+			if err := f.printf("\n"); err != nil {
+				return "", errgo.Mask(err)
+			}
+			return string(data), nil
+		}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", errgo.Notef(err, "cannot read input")
+	}
+	return strings.TrimRight(line, "\n"), nil
+}
+
+// SortedFields returns the given fields sorted into the canonical
+// prompting order used by the fillers in this package: first by
+// group name, then with secret fields after non-secret ones within
+// a group, then by any explicit order and finally by name (see
+// FieldLess). Consumers building custom prompters can use it to
+// iterate fields in exactly the order the fillers do.
+func SortedFields(fields environschema.Fields) []NamedAttr {
+	attrs := make(namedAttrs, 0, len(fields))
+	for name, attr := range fields {
+		attrs = append(attrs, NamedAttr{
+			Name: name,
+			Attr: attr,
+		})
+	}
+	sort.Sort(attrs)
+	return attrs
+}
+
+// EnvConfigurable returns the fields in fields that declare at least
+// one environment variable, sorted into the same order used when
+// prompting. It can be used to generate "Environment Variables"
+// documentation sections.
+func EnvConfigurable(fields environschema.Fields) []NamedAttr {
+	attrs := make(namedAttrs, 0, len(fields))
+	for name, attr := range fields {
+		if attr.EnvVar == "" && len(attr.EnvVars) == 0 {
+			continue
+		}
+		attrs = append(attrs, NamedAttr{
+			Name: name,
+			Attr: attr,
+		})
+	}
+	sort.Sort(attrs)
+	return attrs
+}
+
+// SortedFieldsInGroup returns the fields in fields that belong to the
+// given group, sorted into the same prompting order used when filling
+// a whole form: secret fields after non-secret ones, then by name.
+// It is intended for group-at-a-time wizards that present one group
+// per page.
+func SortedFieldsInGroup(fields environschema.Fields, g environschema.Group) []NamedAttr {
+	attrs := make(namedAttrs, 0, len(fields))
+	for name, attr := range fields {
+		if attr.Group != g {
+			continue
+		}
+		attrs = append(attrs, NamedAttr{
+			Name: name,
+			Attr: attr,
+		})
+	}
+	sort.Sort(attrs)
+	return attrs
+}
+
+// namedAttrs sorts a slice of NamedAttrs into form prompting order.
+type namedAttrs []NamedAttr
+
+func (a namedAttrs) Len() int {
+	return len(a)
+}
+
+func (a namedAttrs) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+func (a namedAttrs) Less(i, j int) bool {
+	return FieldLess(a[i], a[j])
+}
+
+// FieldLess reports whether a0 sorts before a1 in the canonical
+// prompting order used by SortedFields: by group name, with secret
+// fields after non-secret ones within a group, then by any explicit
+// order (fields with one come before fields without, lower values
+// first) and finally by name.
+func FieldLess(a0, a1 NamedAttr) bool {
+	if a0.Group != a1.Group {
+		return a0.Group < a1.Group
+	}
+	if a0.Secret != a1.Secret {
+		return a1.Secret
+	}
+	if a0.Order != a1.Order {
+		if a0.Order == 0 || a1.Order == 0 {
+			return a1.Order == 0
+		}
+		return a0.Order < a1.Order
+	}
+	return a0.Name < a1.Name
+}
+
+// DefaultFromEnv returns any default value found in the environment
+// for the given attribute, falling back to the attribute's static
+// Default value if no environment variable provides one.
+//
+// The environment variables specified in the attribute are checked in
+// order and the first non-empty value found is coerced using the given
+// checker and returned.
+func DefaultFromEnv(attr NamedAttr, checker schema.Checker) (val interface{}, display string, err error) {
+	env, v := defaultFromEnv(attr.Attr)
+	if env == "" {
+		if attr.Default == nil {
+			return nil, "", nil
+		}
+		val, err = checker.Coerce(attr.Default, nil)
+		if err != nil {
+			return nil, "", errgo.Notef(err, "invalid default value for %s", attr.Name)
+		}
+		return val, "", nil
+	}
+	val, err = checker.Coerce(v, nil)
+	if err != nil {
+		return nil, "", errgo.Notef(err, "cannot convert $%s", env)
+	}
+	return val, "", nil
+}
+
+// CoercedDefaultFromEnv returns the default value found in the
+// environment for the given attribute coerced to the attribute's
+// type, along with the name of the environment variable it came
+// from. It returns (nil, "", nil) when no variable provides a
+// value, and a non-nil error when a variable provides a value
+// that cannot be coerced, so callers can distinguish "no default"
+// from "invalid default".
+func CoercedDefaultFromEnv(attr environschema.Attr) (interface{}, string, error) {
+	env, v := defaultFromEnv(attr)
+	if env == "" {
+		return nil, "", nil
+	}
+	checker, err := attr.Checker()
+	if err != nil {
+		return nil, env, errgo.Mask(err)
+	}
+	val, err := checker.Coerce(v, nil)
+	if err != nil {
+		return nil, env, errgo.Notef(err, "cannot convert $%s", env)
+	}
+	return val, env, nil
+}
+
+// defaultFromEnv returns the value of the first environment
+// variable mentioned by attr that is set to a non-empty value,
+// and the name of that variable.
+func defaultFromEnv(attr environschema.Attr) (envVar string, val string) {
+	if attr.EnvVar != "" {
+		if val = os.Getenv(attr.EnvVar); val != "" {
+			return attr.EnvVar, val
+		}
+	}
+	for _, envVar := range attr.EnvVars {
+		if val = os.Getenv(envVar); val != "" {
+			return envVar, val
+		}
+	}
+	return "", ""
+}
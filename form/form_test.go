@@ -0,0 +1,292 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// fieldNames returns the names of the given attributes in order.
+func fieldNames(attrs []NamedAttr) []string {
+	names := make([]string, len(attrs))
+	for i, attr := range attrs {
+		names[i] = attr.Name
+	}
+	return names
+}
+
+func TestSortedFieldsInGroup(t *testing.T) {
+	fields := environschema.Fields{
+		"user":     {Type: environschema.Tstring, Group: environschema.AccountGroup},
+		"token":    {Type: environschema.Tstring, Group: environschema.AccountGroup, Secret: true},
+		"password": {Type: environschema.Tstring, Group: environschema.AccountGroup, Secret: true},
+		"region":   {Type: environschema.Tstring, Group: environschema.ProviderGroup},
+	}
+	got := fieldNames(SortedFieldsInGroup(fields, environschema.AccountGroup))
+	want := []string{"user", "password", "token"}
+	if len(got) != len(want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %#v, want %#v", got, want)
+		}
+	}
+}
+
+func TestFillWarnsShadowedVars(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"a": {Type: environschema.Tstring, EnvVar: "TEST_FORM_SHARED_VAR"},
+			"b": {Type: environschema.Tstring, EnvVar: "TEST_FORM_SHARED_VAR"},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:               strings.NewReader("x\ny\n"),
+		Out:              &out,
+		WarnShadowedVars: true,
+	}
+	if _, err := f.Fill(form); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Warning: $TEST_FORM_SHARED_VAR provides a default for several attributes: a, b.\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output %q does not contain %q", out.String(), want)
+	}
+}
+
+func TestFillNoShadowWarningWithoutOption(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"a": {Type: environschema.Tstring, EnvVar: "TEST_FORM_SHARED_VAR"},
+			"b": {Type: environschema.Tstring, EnvVar: "TEST_FORM_SHARED_VAR"},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:  strings.NewReader("x\ny\n"),
+		Out: &out,
+	}
+	if _, err := f.Fill(form); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "Warning:") {
+		t.Errorf("output %q contains an unexpected warning", out.String())
+	}
+}
+
+func TestMissingMandatoryForm(t *testing.T) {
+	fields := environschema.Fields{
+		"user":     {Type: environschema.Tstring, Mandatory: true},
+		"password": {Type: environschema.Tstring, Mandatory: true, Secret: true},
+		"region":   {Type: environschema.Tstring},
+	}
+	values := map[string]interface{}{
+		"user": "alice",
+	}
+	form := MissingMandatoryForm(fields, values, "Finish setup")
+	if form.Title != "Finish setup" {
+		t.Errorf("got title %q, want %q", form.Title, "Finish setup")
+	}
+	if len(form.Fields) != 1 {
+		t.Fatalf("got fields %#v, want just password", form.Fields)
+	}
+	if _, ok := form.Fields["password"]; !ok {
+		t.Errorf("got fields %#v, want just password", form.Fields)
+	}
+}
+
+func TestMissingMandatoryFormComplete(t *testing.T) {
+	fields := environschema.Fields{
+		"user": {Type: environschema.Tstring, Mandatory: true},
+	}
+	form := MissingMandatoryForm(fields, map[string]interface{}{"user": "alice"}, "")
+	if len(form.Fields) != 0 {
+		t.Errorf("got fields %#v, want none", form.Fields)
+	}
+}
+
+func TestEnvConfigurable(t *testing.T) {
+	fields := environschema.Fields{
+		"user":   {Type: environschema.Tstring, EnvVar: "TEST_USER"},
+		"token":  {Type: environschema.Tstring, EnvVars: []string{"TEST_TOKEN"}},
+		"region": {Type: environschema.Tstring},
+	}
+	got := fieldNames(EnvConfigurable(fields))
+	want := []string{"token", "user"}
+	if len(got) != len(want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %#v, want %#v", got, want)
+		}
+	}
+}
+
+func TestFillShowsHintAfterInvalidInput(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"port": {Type: environschema.Tint},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:  strings.NewReader("not-a-number\n8080\n"),
+		Out: &out,
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vals["port"] != 8080 {
+		t.Errorf("got %#v, want 8080", vals["port"])
+	}
+	if !strings.Contains(out.String(), DefaultHints[environschema.Tint]) {
+		t.Errorf("output %q does not contain the Tint hint %q", out.String(), DefaultHints[environschema.Tint])
+	}
+}
+
+func TestFillCustomHints(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"port": {Type: environschema.Tint},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:  strings.NewReader("not-a-number\n8080\n"),
+		Out: &out,
+		Hints: map[environschema.FieldType]string{
+			environschema.Tint: "Ports are numbers.",
+		},
+	}
+	if _, err := f.Fill(form); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Ports are numbers.") {
+		t.Errorf("output %q does not contain the custom hint", out.String())
+	}
+}
+
+func TestFillShowsRetriesLeft(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"port": {Type: environschema.Tint},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:              strings.NewReader("a\nb\n42\n"),
+		Out:             &out,
+		MaxTries:        3,
+		ShowRetriesLeft: true,
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vals["port"] != 42 {
+		t.Errorf("got %#v, want 42", vals["port"])
+	}
+	if !strings.Contains(out.String(), "(2 attempts left)") {
+		t.Errorf("output %q does not show the first retry count", out.String())
+	}
+	if !strings.Contains(out.String(), "(1 attempt left)") {
+		t.Errorf("output %q does not show the singular retry count", out.String())
+	}
+}
+
+func TestFillShowsExample(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"region": {Type: environschema.Tstring, Example: "us-east-1"},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:  strings.NewReader("eu-west-1\n"),
+		Out: &out,
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vals["region"] != "eu-west-1" {
+		t.Errorf("got %#v, want the entered value", vals["region"])
+	}
+	if !strings.Contains(out.String(), "region (e.g. us-east-1): ") {
+		t.Errorf("prompt %q does not show the example value", out.String())
+	}
+}
+
+// setenv sets the given environment variable for the duration
+// of a test, returning a function that restores its old value.
+func setenv(name, value string) func() {
+	old, wasSet := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if wasSet {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestCoercedDefaultFromEnv(t *testing.T) {
+	attr := environschema.Attr{
+		Type:   environschema.Tint,
+		EnvVar: "TEST_FORM_PORT",
+	}
+	defer setenv("TEST_FORM_PORT", "8080")()
+	val, env, err := CoercedDefaultFromEnv(attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != 8080 {
+		t.Errorf("got %#v, want the coerced value", val)
+	}
+	if env != "TEST_FORM_PORT" {
+		t.Errorf("got env %q, want the providing variable", env)
+	}
+}
+
+func TestCoercedDefaultFromEnvUnset(t *testing.T) {
+	attr := environschema.Attr{
+		Type:   environschema.Tint,
+		EnvVar: "TEST_FORM_PORT",
+	}
+	os.Unsetenv("TEST_FORM_PORT")
+	val, env, err := CoercedDefaultFromEnv(attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != nil || env != "" {
+		t.Errorf("got (%#v, %q), want no default", val, env)
+	}
+}
+
+func TestCoercedDefaultFromEnvInvalid(t *testing.T) {
+	attr := environschema.Attr{
+		Type:   environschema.Tint,
+		EnvVar: "TEST_FORM_PORT",
+	}
+	defer setenv("TEST_FORM_PORT", "not a number")()
+	_, env, err := CoercedDefaultFromEnv(attr)
+	if err == nil {
+		t.Fatalf("expected error for uncoercible value")
+	}
+	// The variable name is returned so the caller can
+	// report where the bad value came from.
+	if env != "TEST_FORM_PORT" {
+		t.Errorf("got env %q, want the providing variable", env)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+)
+
+// JSONFiller is a Filler that fills out a form from a JSON document
+// rather than by prompting, for non-interactive use. The document
+// must hold a JSON object with one member per attribute.
+type JSONFiller struct {
+	// Source is read for the JSON document.
+	Source io.Reader
+}
+
+// Fill implements Filler.Fill by decoding a JSON object from
+// f.Source and coercing each member to its attribute's type. It
+// returns an error naming any keys in the document that are not in
+// the form's fields, and an error naming any mandatory fields that
+// the document omits.
+func (f JSONFiller) Fill(form Form) (map[string]interface{}, error) {
+	dec := json.NewDecoder(f.Source)
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errgo.Notef(err, "cannot decode JSON document")
+	}
+	var unknown []string
+	for name := range doc {
+		if _, ok := form.Fields[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, errgo.Newf("unknown keys in JSON document: %s", strings.Join(unknown, ", "))
+	}
+	values := make(map[string]interface{}, len(doc))
+	for name, attr := range form.Fields {
+		v, ok := doc[name]
+		if !ok {
+			continue
+		}
+		checker, err := attr.Checker()
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid field %s", name)
+		}
+		cv, err := checker.Coerce(v, []string{name})
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		values[name] = cv
+	}
+	if missing := form.Fields.MissingMandatory(values); len(missing) > 0 {
+		return nil, errgo.Newf("missing mandatory fields: %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
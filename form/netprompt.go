@@ -0,0 +1,54 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/errgo.v1"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// DefaultMaxResponseLen holds the default bound on the length of
+// a response line read by NetPrompter.
+const DefaultMaxResponseLen = 4096
+
+// NetPrompter is a Prompter that frames prompts and responses as
+// newline-delimited messages over an io.ReadWriter, which a net.Conn
+// satisfies, allowing a form to drive a remote client.
+//
+// Unlike IOPrompter it makes no terminal assumptions: secret
+// attributes are read like any other, so the connection itself is
+// expected to provide privacy. Responses are bounded in length to
+// protect against misbehaving peers.
+type NetPrompter struct {
+	// Conn holds the connection on which prompts are written
+	// and responses read.
+	Conn io.ReadWriter
+
+	// MaxResponseLen holds the maximum acceptable length in
+	// bytes of a response line. If this is 0 then
+	// DefaultMaxResponseLen will be used.
+	MaxResponseLen int
+}
+
+// Prompt implements Prompter.Prompt by writing the attribute name as
+// a newline-terminated prompt message and reading a single response
+// line in reply.
+func (p NetPrompter) Prompt(name string, attr environschema.Attr) (string, error) {
+	if _, err := fmt.Fprintf(p.Conn, "%s:\n", name); err != nil {
+		return "", errgo.Notef(err, "cannot write prompt")
+	}
+	max := p.MaxResponseLen
+	if max == 0 {
+		max = DefaultMaxResponseLen
+	}
+	line, err := readBoundedLine(p.Conn, max)
+	if err != nil {
+		return "", errgo.Notef(err, "cannot read response")
+	}
+	return line, nil
+}
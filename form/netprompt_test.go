@@ -0,0 +1,52 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// rwPair combines a Reader and a Writer into the io.ReadWriter
+// expected by NetPrompter, simulating the two directions of a
+// connection.
+type rwPair struct {
+	io.Reader
+	io.Writer
+}
+
+func TestNetPrompter(t *testing.T) {
+	var out bytes.Buffer
+	p := NetPrompter{
+		Conn: rwPair{strings.NewReader("secret-value\n"), &out},
+	}
+	resp, err := p.Prompt("token", environschema.Attr{Type: environschema.Tstring})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "secret-value" {
+		t.Errorf("got response %q, want %q", resp, "secret-value")
+	}
+	if got, want := out.String(), "token:\n"; got != want {
+		t.Errorf("got prompt %q, want %q", got, want)
+	}
+}
+
+func TestNetPrompterBoundedResponse(t *testing.T) {
+	p := NetPrompter{
+		Conn:           rwPair{strings.NewReader(strings.Repeat("x", 100) + "\n"), &bytes.Buffer{}},
+		MaxResponseLen: 10,
+	}
+	_, err := p.Prompt("token", environschema.Attr{Type: environschema.Tstring})
+	if err == nil {
+		t.Fatalf("expected error for over-long response")
+	}
+	if !strings.Contains(err.Error(), "line too long") {
+		t.Errorf("error %q does not mention the length limit", err)
+	}
+}
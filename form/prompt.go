@@ -0,0 +1,606 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+	"gopkg.in/errgo.v1"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// Prompter is used by PromptingFiller to ask the user
+// for the value of a single attribute.
+type Prompter interface {
+	// Prompt asks the user for the value of the attribute
+	// with the given name, returning the raw string that
+	// the user entered. The string will subsequently be
+	// coerced to the attribute's type by the filler.
+	Prompt(name string, attr environschema.Attr) (string, error)
+}
+
+// GroupShower is optionally implemented by Prompters that can
+// display a header when prompting moves from one attribute
+// group to the next (see PromptingFiller.ShowGroups).
+type GroupShower interface {
+	// ShowGroup displays a header for the given group.
+	ShowGroup(group environschema.Group) error
+}
+
+// InvalidNotifier is optionally implemented by Prompters that can
+// tell the user why their input was rejected. PromptingFiller
+// calls it before re-prompting when a response fails coercion.
+type InvalidNotifier interface {
+	// NotifyInvalid reports that the response entered for the
+	// attribute with the given name was rejected with the
+	// given error.
+	NotifyInvalid(name string, attr environschema.Attr, err error)
+}
+
+// InputHider is optionally implemented by Prompters that can
+// report whether they are able to hide (not echo) input.
+// Prompters that do not implement it are assumed to be capable
+// of hiding input.
+type InputHider interface {
+	// CanHideInput reports whether input read by the
+	// prompter can be hidden from the user's display.
+	CanHideInput() bool
+}
+
+// CheckSecretInput checks, before the form is run, that the given
+// prompter is able to hide input if the form contains any secret
+// fields, so that secrets are not accidentally echoed to a log.
+// It returns an error naming a secret field if the prompter
+// reports that it cannot hide input.
+func (f Form) CheckSecretInput(p Prompter) error {
+	hider, ok := p.(InputHider)
+	if !ok || hider.CanHideInput() {
+		return nil
+	}
+	for _, field := range SortedFields(f.Fields) {
+		if field.Secret {
+			return errgo.Newf("prompter cannot hide input for secret field %s", field.Name)
+		}
+	}
+	return nil
+}
+
+// PromptingFiller fills out a form by prompting for each field in
+// turn using its Prompter. Unlike IOFiller it does not implement any
+// presentation logic itself - everything the user sees is mediated by
+// the Prompter.
+type PromptingFiller struct {
+	// Prompter is used to ask the user for values. If it is
+	// nil, an IOPrompter using os.Stdin and os.Stdout will
+	// be used.
+	Prompter Prompter
+
+	// MaxTries is the number of times to attempt to get a valid
+	// response when prompting. If this is 0 then the default of 3
+	// attempts will be used.
+	MaxTries int
+
+	// ShowGroups holds whether a header is displayed whenever
+	// prompting moves from one attribute group to the next, if
+	// the Prompter implements GroupShower.
+	ShowGroups bool
+
+	// ConfirmSecret holds whether a second, confirming prompt
+	// ("Confirm name") is issued for each secret attribute that
+	// a value is entered for. A mismatch between the two
+	// responses is treated as invalid input, causing a
+	// re-prompt that counts against MaxTries; neither response
+	// is echoed.
+	ConfirmSecret bool
+
+	// CheckURLs holds the names of attributes whose values are
+	// URLs that should be checked for reachability with a HEAD
+	// request after coercion. An unreachable URL causes a
+	// re-prompt just like invalid input. This is strictly
+	// opt-in; validation schemas remain pure and offline.
+	CheckURLs []string
+
+	// HTTPClient is used for URL reachability checks. If it
+	// is nil, a client with a 5 second timeout is used.
+	HTTPClient *http.Client
+}
+
+// Fill implements Filler.Fill by prompting for each field
+// in turn and coercing the response to the field's type.
+// All field types are supported, including Tattrs, whose
+// responses are entered as whitespace-separated key=value
+// pairs and coerced to a map.
+func (f *PromptingFiller) Fill(form Form) (map[string]interface{}, error) {
+	prompter := f.Prompter
+	if prompter == nil {
+		prompter = IOPrompter{
+			In:  os.Stdin,
+			Out: os.Stdout,
+		}
+	}
+	maxTries := f.MaxTries
+	if maxTries == 0 {
+		maxTries = 3
+	}
+	fields := SortedFields(form.Fields)
+	if err := checkFieldReferences(fields); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	values := make(map[string]interface{}, len(fields))
+	notifier, _ := prompter.(InvalidNotifier)
+	shower, _ := prompter.(GroupShower)
+	var group environschema.Group
+	for i, field := range fields {
+		if f.ShowGroups && shower != nil && (i == 0 || field.Group != group) {
+			group = field.Group
+			if err := shower.ShowGroup(group); err != nil {
+				return nil, errgo.Notef(err, "cannot complete form")
+			}
+		}
+		checker, err := field.Checker()
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid field %s", field.Name)
+		}
+		var v interface{}
+		for i := 0; ; i++ {
+			if i >= maxTries {
+				return nil, errgo.Newf("cannot complete form: too many invalid inputs for %s", field.Name)
+			}
+			line, err := prompter.Prompt(field.Name, field.Attr)
+			if err != nil {
+				return nil, errgo.Notef(err, "cannot complete form")
+			}
+			if line == "" && field.DefaultFromField != "" {
+				if prev, ok := values[field.DefaultFromField]; ok {
+					v = prev
+					break
+				}
+			}
+			if line == "" && !field.Mandatory {
+				// An empty response to an attrs field yields an
+				// empty map rather than no value at all.
+				if field.Type == environschema.Tattrs {
+					v, _ = checker.Coerce(line, nil)
+				}
+				break
+			}
+			if f.ConfirmSecret && field.Secret {
+				confirm, err := prompter.Prompt("Confirm "+field.Name, field.Attr)
+				if err != nil {
+					return nil, errgo.Notef(err, "cannot complete form")
+				}
+				if confirm != line {
+					if notifier != nil {
+						notifier.NotifyInvalid(field.Name, field.Attr, errgo.New("secret values do not match"))
+					}
+					continue
+				}
+			}
+			v, err = checker.Coerce(line, nil)
+			if err == nil {
+				err = f.checkURL(field.Name, v)
+			}
+			if err == nil {
+				break
+			}
+			if notifier != nil {
+				notifier.NotifyInvalid(field.Name, field.Attr, err)
+			}
+		}
+		if v != nil {
+			values[field.Name] = v
+		}
+	}
+	return values, nil
+}
+
+// checkFieldReferences checks that every field naming another field
+// in DefaultFromField refers to a field that is prompted for earlier,
+// so that the referenced value is available when the default is
+// resolved.
+func checkFieldReferences(fields []NamedAttr) error {
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if ref := field.DefaultFromField; ref != "" && !seen[ref] {
+			return errgo.Newf("field %s refers to %q which is not prompted for before it", field.Name, ref)
+		}
+		seen[field.Name] = true
+	}
+	return nil
+}
+
+// checkURL checks that the value of the named attribute is a
+// reachable URL if the attribute is listed in f.CheckURLs,
+// and returns nil otherwise.
+func (f *PromptingFiller) checkURL(name string, v interface{}) error {
+	check := false
+	for _, n := range f.CheckURLs {
+		if n == name {
+			check = true
+			break
+		}
+	}
+	if !check {
+		return nil
+	}
+	url, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	client := f.HTTPClient
+	if client == nil {
+		client = &http.Client{
+			Timeout: 5 * time.Second,
+		}
+	}
+	resp, err := client.Head(url)
+	if err != nil {
+		return errgo.Notef(err, "URL %q is not reachable", url)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// IOPrompter is a Prompter based around an io.Reader and io.Writer.
+type IOPrompter struct {
+	// In is used to read responses from the user.
+	In io.Reader
+
+	// Out is used to write prompts to the user.
+	Out io.Writer
+
+	// Quiet holds whether prompt text is suppressed when In is
+	// not a terminal, so that piping responses to the prompter
+	// does not clutter the output with prompts.
+	Quiet bool
+
+	// Color holds whether output should be colorized with ANSI
+	// escape sequences. Colorization is automatically disabled
+	// when Out is not a terminal, so non-terminal output never
+	// contains escape sequences.
+	Color bool
+
+	// Theme holds the escape sequences used when Color is true.
+	// If it is the zero Theme, DefaultTheme is used.
+	Theme Theme
+
+	// EchoMask holds a rune that is echoed for each character
+	// typed in response to a secret prompt, so that the user
+	// gets per-keystroke feedback (for example '*'). It only
+	// takes effect when In is a terminal; otherwise input is
+	// read as usual. If it is zero, secret input is read with
+	// no echo at all.
+	EchoMask rune
+
+	// AcceptMultilineSecrets holds whether responses for secret
+	// attributes that are marked multi-line (Secret and
+	// Multiline both set) are read until end of file rather
+	// than to the end of the line, so that multi-line values
+	// such as private keys can be pasted. Single-line secrets
+	// are unaffected. As for single-line secrets, echo is
+	// disabled when the input is a terminal.
+	AcceptMultilineSecrets bool
+
+	// AcceptSecretFiles holds whether a response of the form
+	// "@/path/to/file" for a secret attribute names a file whose
+	// contents are used as the value, which is convenient for
+	// values such as private keys. A plain response is used
+	// literally; an unreadable file causes a re-prompt.
+	AcceptSecretFiles bool
+}
+
+// useColor reports whether output should be colorized.
+func (p IOPrompter) useColor() bool {
+	return p.Color && isTerminal(p.Out)
+}
+
+// theme returns the theme to use, substituting
+// DefaultTheme for the zero Theme.
+func (p IOPrompter) theme() Theme {
+	if p.Theme == (Theme{}) {
+		return DefaultTheme
+	}
+	return p.Theme
+}
+
+// Prompt implements Prompter.Prompt by writing the attribute
+// name to p.Out and reading a line of input from p.In. If p.In
+// is a terminal and the attribute is secret, echo will be
+// disabled while the response is read.
+//
+// If the attribute enumerates its possible values, they are
+// printed as a numbered menu before the prompt and the response
+// may be either the number of an option or the option itself,
+// with a number taking precedence.
+func (p IOPrompter) Prompt(name string, attr environschema.Attr) (string, error) {
+	quiet := p.suppressPrompts()
+	if len(attr.Values) > 0 && !quiet {
+		for i, v := range attr.Values {
+			if _, err := fmt.Fprintf(p.Out, "%d) %v\n", i+1, v); err != nil {
+				return "", errgo.Notef(err, "cannot write prompt")
+			}
+		}
+	}
+	for try := 0; ; try++ {
+		label := name
+		if p.useColor() {
+			label = colorize(p.theme().Label, label)
+		}
+		if !quiet {
+			switch {
+			case attr.Multiline && !attr.Secret:
+				if _, err := fmt.Fprintf(p.Out, "%s (finish with a line containing only \".\" or end of file):\n", label); err != nil {
+					return "", errgo.Notef(err, "cannot write prompt")
+				}
+			case attr.Multiline && attr.Secret && p.AcceptMultilineSecrets:
+				if _, err := fmt.Fprintf(p.Out, "%s (finish with end of file):\n", label); err != nil {
+					return "", errgo.Notef(err, "cannot write prompt")
+				}
+			default:
+				if _, err := fmt.Fprintf(p.Out, "%s: ", label); err != nil {
+					return "", errgo.Notef(err, "cannot write prompt")
+				}
+			}
+		}
+		resp, err := p.read(attr)
+		if err != nil {
+			return "", errgo.Mask(err)
+		}
+		// A response to an enumerated field may be the number of
+		// an option rather than the option itself.
+		if len(attr.Values) > 0 {
+			if n, err := strconv.Atoi(strings.TrimSpace(resp)); err == nil && n >= 1 && n <= len(attr.Values) {
+				return fmt.Sprint(attr.Values[n-1]), nil
+			}
+		}
+		if !p.AcceptSecretFiles || !attr.Secret || !strings.HasPrefix(resp, "@") {
+			return resp, nil
+		}
+		data, err := ioutil.ReadFile(resp[1:])
+		if err == nil {
+			return strings.TrimSuffix(string(data), "\n"), nil
+		}
+		if try >= 2 {
+			return "", errgo.Notef(err, "cannot read secret file")
+		}
+		if _, err := fmt.Fprintf(p.Out, "cannot read %q: %v\n", resp[1:], err); err != nil {
+			return "", errgo.Notef(err, "cannot write output")
+		}
+	}
+}
+
+// ShowGroup implements GroupShower.ShowGroup by printing a
+// header line for the group. Nothing is printed for the empty
+// (provider) group.
+func (p IOPrompter) ShowGroup(group environschema.Group) error {
+	if group == "" {
+		return nil
+	}
+	if _, err := fmt.Fprintf(p.Out, "-- %s --\n", group); err != nil {
+		return errgo.Notef(err, "cannot write output")
+	}
+	return nil
+}
+
+// NotifyInvalid implements InvalidNotifier.NotifyInvalid by
+// printing the reason the response was rejected to p.Out.
+func (p IOPrompter) NotifyInvalid(name string, attr environschema.Attr, err error) {
+	msg := fmt.Sprintf("Invalid input: %v", err)
+	if p.useColor() {
+		msg = colorize(p.theme().Error, msg)
+	}
+	fmt.Fprintf(p.Out, "%s\n", msg)
+}
+
+// suppressPrompts reports whether prompt text should be
+// suppressed because input is not interactive (see Quiet).
+func (p IOPrompter) suppressPrompts() bool {
+	if !p.Quiet {
+		return false
+	}
+	file, ok := p.In.(*os.File)
+	return !ok || !terminal.IsTerminal(int(file.Fd()))
+}
+
+// CanHideInput implements InputHider.CanHideInput by reporting
+// whether p.In is a terminal, since echo can only be disabled
+// on a terminal.
+func (p IOPrompter) CanHideInput() bool {
+	file, ok := p.In.(*os.File)
+	return ok && terminal.IsTerminal(int(file.Fd()))
+}
+
+// read reads a single response from p.In, hiding the input
+// if the attribute is secret and the input is a terminal.
+func (p IOPrompter) read(attr environschema.Attr) (string, error) {
+	if attr.Secret && attr.Multiline && p.AcceptMultilineSecrets {
+		return p.readMultilineSecret()
+	}
+	if attr.Multiline && !attr.Secret {
+		return p.readMultiline()
+	}
+	if attr.Secret {
+		if file, ok := p.In.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+			if p.EchoMask != 0 {
+				return p.readMasked(file)
+			}
+			data, err := terminal.ReadPassword(int(file.Fd()))
+			if err != nil {
+				return "", errgo.Notef(err, "cannot read secret input")
+			}
+			if _, err := fmt.Fprintln(p.Out); err != nil {
+				return "", errgo.Notef(err, "cannot write output")
+			}
+			return string(data), nil
+		}
+	}
+	line, err := readLine(p.In)
+	if err != nil {
+		return "", errgo.Notef(err, "cannot read input")
+	}
+	return line, nil
+}
+
+// readMasked reads a line from the given terminal with the
+// terminal in raw mode, echoing p.EchoMask for each character
+// typed. Backspace removes the most recent character.
+func (p IOPrompter) readMasked(file *os.File) (string, error) {
+	fd := int(file.Fd())
+	oldState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return "", errgo.Notef(err, "cannot read secret input")
+	}
+	defer terminal.Restore(fd, oldState)
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			switch c := buf[0]; c {
+			case '\r', '\n':
+				if _, err := fmt.Fprint(p.Out, "\r\n"); err != nil {
+					return "", errgo.Notef(err, "cannot write output")
+				}
+				return string(line), nil
+			case '\b', 0x7f:
+				if len(line) > 0 {
+					line = line[:len(line)-1]
+					if _, err := fmt.Fprint(p.Out, "\b \b"); err != nil {
+						return "", errgo.Notef(err, "cannot write output")
+					}
+				}
+			default:
+				line = append(line, c)
+				if _, err := fmt.Fprintf(p.Out, "%c", p.EchoMask); err != nil {
+					return "", errgo.Notef(err, "cannot write output")
+				}
+			}
+		}
+		if err == io.EOF {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", errgo.Notef(err, "cannot read secret input")
+		}
+	}
+}
+
+// readMultiline reads lines from p.In until a line containing
+// only "." or end of file, returning the collected lines joined
+// with newlines so that internal newlines are preserved.
+func (p IOPrompter) readMultiline() (string, error) {
+	var lines []string
+	for {
+		line, eof, err := readLineOrEOF(p.In)
+		if err != nil {
+			return "", errgo.Notef(err, "cannot read input")
+		}
+		if line == "." {
+			break
+		}
+		if line != "" || !eof {
+			lines = append(lines, line)
+		}
+		if eof {
+			break
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// readMultilineSecret reads lines from p.In until end of file,
+// returning them joined with newlines. If the input is a terminal,
+// echo is disabled for every line.
+func (p IOPrompter) readMultilineSecret() (string, error) {
+	if file, ok := p.In.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+		var lines []string
+		for {
+			data, err := terminal.ReadPassword(int(file.Fd()))
+			if len(data) > 0 {
+				lines = append(lines, string(data))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", errgo.Notef(err, "cannot read secret input")
+			}
+		}
+		if _, err := fmt.Fprintln(p.Out); err != nil {
+			return "", errgo.Notef(err, "cannot write output")
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	data, err := ioutil.ReadAll(p.In)
+	if err != nil {
+		return "", errgo.Notef(err, "cannot read input")
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// readLine reads a single line from r without reading
+// beyond the line terminator, so that subsequent prompts
+// can read from the same reader.
+func readLine(r io.Reader) (string, error) {
+	return readBoundedLine(r, 0)
+}
+
+// readBoundedLine reads a single line from r without reading beyond
+// the line terminator, so that subsequent prompts can read from the
+// same reader. If max is greater than zero, it returns an error if
+// the line exceeds max bytes.
+func readBoundedLine(r io.Reader, max int) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+			if max > 0 && len(line) > max {
+				return "", errgo.Newf("line too long (limit %d bytes)", max)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errgo.Mask(err)
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+// readLineOrEOF is like readLine except that it also reports
+// whether end of file was reached on the line.
+func readLineOrEOF(r io.Reader) (line string, eof bool, err error) {
+	var data []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return strings.TrimRight(string(data), "\r"), false, nil
+			}
+			data = append(data, buf[0])
+		}
+		if err == io.EOF {
+			return strings.TrimRight(string(data), "\r"), true, nil
+		}
+		if err != nil {
+			return "", false, errgo.Mask(err)
+		}
+	}
+}
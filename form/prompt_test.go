@@ -0,0 +1,387 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+var errUnreachable = errors.New("network down")
+
+// stubRoundTripper is an http.RoundTripper that fails every
+// request with err if err is non-nil, and otherwise returns an
+// empty 200 response.
+type stubRoundTripper struct {
+	err error
+}
+
+func (t stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func urlCheckFiller(rt http.RoundTripper, answers ...string) *PromptingFiller {
+	return &PromptingFiller{
+		Prompter:   &ScriptedPrompter{Answers: answers},
+		CheckURLs:  []string{"url"},
+		HTTPClient: &http.Client{Transport: rt},
+		MaxTries:   1,
+	}
+}
+
+func TestCheckURLReachable(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"url": {Type: environschema.Tstring, Mandatory: true},
+		},
+	}
+	f := urlCheckFiller(stubRoundTripper{}, "http://example.com/")
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vals["url"] != "http://example.com/" {
+		t.Errorf("got %#v, want the entered URL", vals["url"])
+	}
+}
+
+func TestCheckURLUnreachable(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"url": {Type: environschema.Tstring, Mandatory: true},
+		},
+	}
+	f := urlCheckFiller(stubRoundTripper{err: errUnreachable}, "http://example.com/")
+	_, err := f.Fill(form)
+	if err == nil {
+		t.Fatalf("expected error for unreachable URL")
+	}
+	if !strings.Contains(err.Error(), "too many invalid inputs for url") {
+		t.Errorf("got error %q, want a too-many-invalid-inputs error", err)
+	}
+}
+
+func TestCheckURLOnlyNamedFields(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"name": {Type: environschema.Tstring, Mandatory: true},
+		},
+	}
+	// The failing client must not be consulted for fields
+	// that are not listed in CheckURLs.
+	f := urlCheckFiller(stubRoundTripper{err: errUnreachable}, "http://example.com/")
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vals["name"] != "http://example.com/" {
+		t.Errorf("got %#v, want the entered value", vals["name"])
+	}
+}
+
+func TestAcceptSecretFiles(t *testing.T) {
+	file, err := ioutil.TempFile("", "environschema-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString("s3cret\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file.Close()
+	p := IOPrompter{
+		In:                strings.NewReader("@" + file.Name() + "\n"),
+		Out:               ioutil.Discard,
+		AcceptSecretFiles: true,
+	}
+	attr := environschema.Attr{Type: environschema.Tstring, Secret: true}
+	resp, err := p.Prompt("token", attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The trailing newline is stripped from the file contents.
+	if resp != "s3cret" {
+		t.Errorf("got %q, want the file contents", resp)
+	}
+}
+
+func TestAcceptSecretFilesLiteralValue(t *testing.T) {
+	p := IOPrompter{
+		In:                strings.NewReader("plain-secret\n"),
+		Out:               ioutil.Discard,
+		AcceptSecretFiles: true,
+	}
+	attr := environschema.Attr{Type: environschema.Tstring, Secret: true}
+	resp, err := p.Prompt("token", attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "plain-secret" {
+		t.Errorf("got %q, want the literal value", resp)
+	}
+}
+
+func TestAcceptSecretFilesUnreadableFile(t *testing.T) {
+	var out bytes.Buffer
+	p := IOPrompter{
+		In:                strings.NewReader("@/nonexistent/secret\nplain-secret\n"),
+		Out:               &out,
+		AcceptSecretFiles: true,
+	}
+	attr := environschema.Attr{Type: environschema.Tstring, Secret: true}
+	resp, err := p.Prompt("token", attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The unreadable file causes a re-prompt; the next
+	// response is used literally.
+	if resp != "plain-secret" {
+		t.Errorf("got %q, want the re-prompted value", resp)
+	}
+	if !strings.Contains(out.String(), "cannot read") {
+		t.Errorf("output %q does not report the unreadable file", out.String())
+	}
+}
+
+// fixedHider wraps a Prompter with a fixed answer to
+// CanHideInput.
+type fixedHider struct {
+	Prompter
+	canHide bool
+}
+
+func (h fixedHider) CanHideInput() bool {
+	return h.canHide
+}
+
+func TestCheckSecretInput(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"user":     {Type: environschema.Tstring},
+			"password": {Type: environschema.Tstring, Secret: true},
+		},
+	}
+	// A non-terminal reader cannot hide input.
+	p := IOPrompter{
+		In:  strings.NewReader(""),
+		Out: ioutil.Discard,
+	}
+	err := form.CheckSecretInput(p)
+	if err == nil {
+		t.Fatalf("expected error for secret field without hidden input")
+	}
+	if !strings.Contains(err.Error(), "password") {
+		t.Errorf("error %q does not name the secret field", err)
+	}
+}
+
+func TestCheckSecretInputCapableHider(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"password": {Type: environschema.Tstring, Secret: true},
+		},
+	}
+	p := fixedHider{
+		Prompter: &ScriptedPrompter{},
+		canHide:  true,
+	}
+	if err := form.CheckSecretInput(p); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckSecretInputIncapableHider(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"password": {Type: environschema.Tstring, Secret: true},
+		},
+	}
+	p := fixedHider{
+		Prompter: &ScriptedPrompter{},
+		canHide:  false,
+	}
+	if err := form.CheckSecretInput(p); err == nil {
+		t.Errorf("expected error for incapable prompter")
+	}
+}
+
+func TestCheckSecretInputNonHiderPrompter(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"password": {Type: environschema.Tstring, Secret: true},
+		},
+	}
+	// A prompter that does not implement InputHider is assumed
+	// to be capable of hiding input.
+	if err := form.CheckSecretInput(&ScriptedPrompter{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMultilineSecretTerminatedByEOF(t *testing.T) {
+	var out bytes.Buffer
+	p := IOPrompter{
+		In:                     strings.NewReader("line1\nline2\nline3"),
+		Out:                    &out,
+		AcceptMultilineSecrets: true,
+	}
+	attr := environschema.Attr{
+		Type:      environschema.Tstring,
+		Secret:    true,
+		Multiline: true,
+	}
+	resp, err := p.Prompt("key", attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "line1\nline2\nline3" {
+		t.Errorf("got %q, want the full multi-line value", resp)
+	}
+	if !strings.Contains(out.String(), "finish with end of file") {
+		t.Errorf("prompt %q does not show the termination convention", out.String())
+	}
+}
+
+func TestSingleLineSecretNotReadToEOF(t *testing.T) {
+	in := strings.NewReader("first\nsecond\n")
+	p := IOPrompter{
+		In:                     in,
+		Out:                    ioutil.Discard,
+		AcceptMultilineSecrets: true,
+	}
+	// The attribute is secret but not multi-line, so only one
+	// line may be consumed.
+	attr := environschema.Attr{
+		Type:   environschema.Tstring,
+		Secret: true,
+	}
+	resp, err := p.Prompt("token", attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "first" {
+		t.Errorf("got %q, want the first line only", resp)
+	}
+	resp, err = p.Prompt("token", attr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "second" {
+		t.Errorf("got %q, want the second line to still be readable", resp)
+	}
+}
+
+func TestDefaultFromField(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"user": {
+				Type:      environschema.Tstring,
+				Mandatory: true,
+				Order:     1,
+			},
+			"backup-user": {
+				Type:             environschema.Tstring,
+				Order:            2,
+				DefaultFromField: "user",
+			},
+		},
+	}
+	f := PromptingFiller{
+		Prompter: &ScriptedPrompter{Answers: []string{"alice", ""}},
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The empty response takes the value already entered
+	// for the referenced field.
+	if vals["backup-user"] != "alice" {
+		t.Errorf("got %#v, want the referenced field's value", vals["backup-user"])
+	}
+}
+
+func TestDefaultFromFieldForwardReference(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"user": {
+				Type:             environschema.Tstring,
+				Order:            2,
+				DefaultFromField: "backup-user",
+			},
+			"backup-user": {
+				Type:  environschema.Tstring,
+				Order: 3,
+			},
+		},
+	}
+	f := PromptingFiller{
+		Prompter: &ScriptedPrompter{Answers: []string{"alice", "bob"}},
+	}
+	_, err := f.Fill(form)
+	if err == nil {
+		t.Fatalf("expected error for forward reference")
+	}
+	if !strings.Contains(err.Error(), "not prompted for before it") {
+		t.Errorf("got error %q, want a forward-reference error", err)
+	}
+}
+
+func TestFillTattrs(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"resources": {Type: environschema.Tattrs},
+		},
+	}
+	f := PromptingFiller{
+		Prompter: &ScriptedPrompter{Answers: []string{"a=1 b=2"}},
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"a": "1", "b": "2"}
+	if !reflect.DeepEqual(vals["resources"], want) {
+		t.Errorf("got %#v, want %#v", vals["resources"], want)
+	}
+}
+
+func TestFillTattrsEmptyAnswer(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"resources": {Type: environschema.Tattrs},
+		},
+	}
+	f := PromptingFiller{
+		Prompter: &ScriptedPrompter{Answers: []string{""}},
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// An empty response yields an empty map rather than
+	// no value at all.
+	v, ok := vals["resources"]
+	if !ok {
+		t.Fatalf("no value for the attrs field")
+	}
+	m, ok := v.(map[string]string)
+	if !ok || len(m) != 0 {
+		t.Errorf("got %#v, want an empty map", v)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// ScriptedPrompter is a Prompter that replays a fixed sequence of
+// answers, for use when testing consumers that embed forms.
+type ScriptedPrompter struct {
+	// Answers holds the responses to return, in prompt order.
+	Answers []string
+
+	next int
+}
+
+// Prompt implements Prompter.Prompt by returning the next answer
+// in the script. It returns an error if the script has run out.
+func (p *ScriptedPrompter) Prompt(name string, attr environschema.Attr) (string, error) {
+	if p.next >= len(p.Answers) {
+		return "", errgo.Newf("no scripted answer for %s", name)
+	}
+	answer := p.Answers[p.next]
+	p.next++
+	return answer, nil
+}
+
+// PromptScript returns the ordered list of answers that would drive
+// a PromptingFiller over the given form to the given result: one
+// answer per field in prompting order, with an empty answer for
+// any field that has no entry in result, which accepts the field's
+// default. Feeding the script to a ScriptedPrompter reproduces
+// the result.
+func PromptScript(f Form, result map[string]interface{}) []string {
+	fields := SortedFields(f.Fields)
+	answers := make([]string, len(fields))
+	for i, field := range fields {
+		if v, ok := result[field.Name]; ok {
+			answers[i] = answerString(v)
+		}
+	}
+	return answers
+}
+
+// answerString returns the single-line answer that coerces
+// back to the given canonical value.
+func answerString(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + "=" + v[k]
+		}
+		return strings.Join(pairs, " ")
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+func TestPromptScriptRoundTrip(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"name":  {Type: environschema.Tstring, Mandatory: true},
+			"port":  {Type: environschema.Tint},
+			"attrs": {Type: environschema.Tattrs},
+			"extra": {Type: environschema.Tstring},
+		},
+	}
+	result := map[string]interface{}{
+		"name": "srv",
+		"port": 8080,
+		"attrs": map[string]string{
+			"a": "1",
+			"b": "2",
+		},
+	}
+	script := PromptScript(form, result)
+	f := PromptingFiller{
+		Prompter: &ScriptedPrompter{Answers: script},
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// "extra" has no entry in result, so its empty answer
+	// accepts the default and it stays absent.
+	if !reflect.DeepEqual(vals, result) {
+		t.Errorf("got %#v, want %#v", vals, result)
+	}
+}
+
+func TestScriptedPrompterRunsOut(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"name": {Type: environschema.Tstring, Mandatory: true},
+		},
+	}
+	f := PromptingFiller{
+		Prompter: &ScriptedPrompter{},
+	}
+	_, err := f.Fill(form)
+	if err == nil {
+		t.Fatalf("expected error when the script runs out")
+	}
+	if !strings.Contains(err.Error(), "no scripted answer for name") {
+		t.Errorf("got error %q, want a script-exhausted error", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"unicode"
+)
+
+// SecretStrength describes the evaluated strength of a secret value.
+type SecretStrength string
+
+// The possible strength values, from weakest to strongest.
+const (
+	StrengthWeak   SecretStrength = "weak"
+	StrengthMedium SecretStrength = "medium"
+	StrengthStrong SecretStrength = "strong"
+)
+
+// EvalStrength returns an indication of the strength of the given
+// secret value, based on its length and the variety of character
+// classes it uses. It never reveals anything about the value other
+// than the returned category.
+func EvalStrength(secret string) SecretStrength {
+	var classes int
+	var hasLower, hasUpper, hasDigit, hasOther bool
+	for _, r := range secret {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasOther = true
+		}
+	}
+	for _, has := range []bool{hasLower, hasUpper, hasDigit, hasOther} {
+		if has {
+			classes++
+		}
+	}
+	n := len(secret)
+	switch {
+	case n >= 12 && classes >= 3:
+		return StrengthStrong
+	case n >= 8 && classes >= 2:
+		return StrengthMedium
+	}
+	return StrengthWeak
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+func TestEvalStrength(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   SecretStrength
+	}{
+		{"", StrengthWeak},
+		{"abc", StrengthWeak},
+		{"abcdefghijkl", StrengthWeak},
+		{"abcd1234", StrengthMedium},
+		{"Abcd1234!xyz", StrengthStrong},
+	}
+	for _, test := range tests {
+		if got := EvalStrength(test.secret); got != test.want {
+			t.Errorf("EvalStrength(%q): got %q, want %q", test.secret, got, test.want)
+		}
+	}
+}
+
+func TestFillShowsSecretStrength(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"password": {Type: environschema.Tstring, Secret: true},
+		},
+	}
+	var out bytes.Buffer
+	f := IOFiller{
+		In:                 strings.NewReader("abcd1234\n"),
+		Out:                &out,
+		ShowSecretStrength: true,
+	}
+	vals, err := f.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vals["password"] != "abcd1234" {
+		t.Errorf("got %#v, want the entered password", vals["password"])
+	}
+	if !strings.Contains(out.String(), "strength: medium") {
+		t.Errorf("output %q does not show the strength indicator", out.String())
+	}
+	// The indicator must never leak the value itself.
+	if strings.Contains(out.String(), "abcd1234") {
+		t.Errorf("output %q leaks the secret value", out.String())
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+)
+
+// DefaultMaxDisplayLen holds the default maximum number of
+// characters used when displaying an attribute value in a
+// summary before it is truncated.
+const DefaultMaxDisplayLen = 60
+
+// Summarizer produces one-line summaries of attribute
+// values, for example when confirming a filled form.
+type Summarizer struct {
+	// MaxValueLen holds the maximum number of characters
+	// of an attribute value that will be displayed. Longer
+	// values are truncated and terminated with "…". If it
+	// is 0, DefaultMaxDisplayLen will be used.
+	MaxValueLen int
+}
+
+// SummaryLine returns a one-line summary of the given
+// attribute value in the form "name: value". Secret values are
+// always masked in full; other values longer than s.MaxValueLen
+// are truncated.
+func (s Summarizer) SummaryLine(attr NamedAttr, val interface{}) string {
+	if attr.Secret {
+		return fmt.Sprintf("%s: *****", attr.Name)
+	}
+	return fmt.Sprintf("%s: %s", attr.Name, truncateDisplay(fmt.Sprint(val), s.maxValueLen()))
+}
+
+// maxValueLen returns the maximum display length to use,
+// substituting the default for the zero value.
+func (s Summarizer) maxValueLen() int {
+	if s.MaxValueLen == 0 {
+		return DefaultMaxDisplayLen
+	}
+	return s.MaxValueLen
+}
+
+// Confirmer wraps a Filler, printing a summary of the filled
+// values and requiring acknowledgement before returning them.
+//
+// Destructive attributes (see environschema.Attr.Destructive) that
+// have been given a value are specifically called out in the summary
+// and the user must type "yes" in full to acknowledge them;
+// otherwise any non-empty response is taken as confirmation.
+type Confirmer struct {
+	// Filler is used to fill the form before confirmation.
+	Filler Filler
+
+	// In is used to read the user's acknowledgement. If this
+	// is nil, then os.Stdin will be used.
+	In io.Reader
+
+	// Out is used to write the summary and confirmation
+	// prompt. If this is nil, then os.Stdout will be used.
+	Out io.Writer
+
+	// Summarizer is used to render each filled value.
+	Summarizer Summarizer
+}
+
+// Fill implements Filler.Fill by filling the form with c.Filler,
+// writing a summary of the values obtained and prompting for
+// confirmation before returning them.
+func (c Confirmer) Fill(f Form) (map[string]interface{}, error) {
+	vals, err := c.Filler.Fill(f)
+	if err != nil {
+		return nil, errgo.Mask(err, errgo.Any)
+	}
+	in := c.In
+	if in == nil {
+		in = os.Stdin
+	}
+	out := c.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	var destructive []string
+	for _, attr := range SortedFields(f.Fields) {
+		val, ok := vals[attr.Name]
+		if !ok {
+			continue
+		}
+		if attr.Destructive {
+			destructive = append(destructive, attr.Name)
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", c.Summarizer.SummaryLine(attr, val)); err != nil {
+			return nil, errgo.Notef(err, "cannot write output")
+		}
+	}
+	prompt := "Proceed?"
+	if len(destructive) > 0 {
+		if _, err := fmt.Fprintf(out, "WARNING: changing %s can destroy data.\n", strings.Join(destructive, ", ")); err != nil {
+			return nil, errgo.Notef(err, "cannot write output")
+		}
+		prompt = `Type "yes" to proceed:`
+	}
+	if _, err := fmt.Fprintf(out, "%s ", prompt); err != nil {
+		return nil, errgo.Notef(err, "cannot write output")
+	}
+	resp, err := readLine(in)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot read confirmation")
+	}
+	resp = strings.ToLower(strings.TrimSpace(resp))
+	if len(destructive) > 0 && resp != "yes" {
+		return nil, errgo.New("form not confirmed")
+	}
+	if resp == "" || resp == "n" || resp == "no" {
+		return nil, errgo.New("form not confirmed")
+	}
+	return vals, nil
+}
+
+// truncateDisplay returns s truncated to at most max
+// characters, with "…" appended when truncation happens.
+func truncateDisplay(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[0:max]) + "…"
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rogpeppe/environschema"
+)
+
+// staticFiller is a Filler that returns a fixed set of values,
+// for testing wrappers such as Confirmer.
+type staticFiller map[string]interface{}
+
+func (f staticFiller) Fill(form Form) (map[string]interface{}, error) {
+	return f, nil
+}
+
+func TestSummaryLineShortValueUntouched(t *testing.T) {
+	attr := NamedAttr{
+		Name: "region",
+		Attr: environschema.Attr{Type: environschema.Tstring},
+	}
+	got := Summarizer{}.SummaryLine(attr, "us-east-1")
+	if want := "region: us-east-1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSummaryLineTruncatesLongValue(t *testing.T) {
+	attr := NamedAttr{
+		Name: "region",
+		Attr: environschema.Attr{Type: environschema.Tstring},
+	}
+	got := Summarizer{MaxValueLen: 8}.SummaryLine(attr, "abcdefghijkl")
+	if want := "region: abcdefgh…"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSummaryLineDefaultMaxDisplayLen(t *testing.T) {
+	attr := NamedAttr{
+		Name: "region",
+		Attr: environschema.Attr{Type: environschema.Tstring},
+	}
+	long := strings.Repeat("x", DefaultMaxDisplayLen+1)
+	got := Summarizer{}.SummaryLine(attr, long)
+	want := "region: " + strings.Repeat("x", DefaultMaxDisplayLen) + "…"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSummaryLineMasksSecret(t *testing.T) {
+	attr := NamedAttr{
+		Name: "password",
+		Attr: environschema.Attr{
+			Type:   environschema.Tstring,
+			Secret: true,
+		},
+	}
+	got := Summarizer{}.SummaryLine(attr, strings.Repeat("s", 100))
+	if want := "password: *****"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConfirmerHighlightsDestructiveFields(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"region": {Type: environschema.Tstring},
+			"disk":   {Type: environschema.Tstring, Destructive: true},
+		},
+	}
+	vals := staticFiller{
+		"region": "us-east-1",
+		"disk":   "reformat",
+	}
+	var out bytes.Buffer
+	c := Confirmer{
+		Filler: vals,
+		In:     strings.NewReader("yes\n"),
+		Out:    &out,
+	}
+	got, err := c.Fill(form)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}(vals)) {
+		t.Errorf("got %#v, want %#v", got, map[string]interface{}(vals))
+	}
+	if !strings.Contains(out.String(), "WARNING: changing disk can destroy data.") {
+		t.Errorf("output %q does not call out the destructive field", out.String())
+	}
+	if !strings.Contains(out.String(), `Type "yes" to proceed:`) {
+		t.Errorf("output %q does not require explicit acknowledgement", out.String())
+	}
+}
+
+func TestConfirmerDestructiveNotAcknowledged(t *testing.T) {
+	form := Form{
+		Fields: environschema.Fields{
+			"disk": {Type: environschema.Tstring, Destructive: true},
+		},
+	}
+	c := Confirmer{
+		Filler: staticFiller{"disk": "reformat"},
+		In:     strings.NewReader("ok\n"),
+		Out:    &bytes.Buffer{},
+	}
+	if _, err := c.Fill(form); err == nil {
+		t.Fatalf(`expected error when acknowledgement is not "yes"`)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// TimeoutFiller wraps a Filler, abandoning the fill if the whole
+// form has not been completed within the given timeout.
+//
+// Note that if the underlying filler is blocked reading input when
+// the timeout expires, the read itself cannot be interrupted; the
+// fill runs in its own goroutine which will terminate when the
+// blocked read eventually completes or its input is closed.
+type TimeoutFiller struct {
+	// Underlying is used to fill the form.
+	Underlying Filler
+
+	// Timeout holds the maximum time allowed for the
+	// whole fill.
+	Timeout time.Duration
+}
+
+// ErrTimeout is the cause of the error returned by
+// TimeoutFiller.Fill when the timeout expires.
+var ErrTimeout = errgo.New("form timed out")
+
+// fillResult holds the result of an underlying fill.
+type fillResult struct {
+	vals map[string]interface{}
+	err  error
+}
+
+// Fill implements Filler.Fill by running the underlying fill,
+// returning an error with an ErrTimeout cause if it does not
+// complete within t.Timeout.
+func (t TimeoutFiller) Fill(f Form) (map[string]interface{}, error) {
+	// The channel is buffered so that the goroutine can always
+	// deliver its result and exit, even after a timeout.
+	done := make(chan fillResult, 1)
+	go func() {
+		vals, err := t.Underlying.Fill(f)
+		done <- fillResult{
+			vals: vals,
+			err:  err,
+		}
+	}()
+	timer := time.NewTimer(t.Timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, errgo.Mask(r.err, errgo.Any)
+		}
+		return r.vals, nil
+	case <-timer.C:
+		return nil, errgo.WithCausef(nil, ErrTimeout, "form not completed within %v", t.Timeout)
+	}
+}
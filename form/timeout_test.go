@@ -0,0 +1,51 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// slowFiller is a Filler that sleeps before returning its values.
+type slowFiller struct {
+	delay time.Duration
+	vals  map[string]interface{}
+}
+
+func (f slowFiller) Fill(form Form) (map[string]interface{}, error) {
+	time.Sleep(f.delay)
+	return f.vals, nil
+}
+
+func TestTimeoutFillerExpires(t *testing.T) {
+	f := TimeoutFiller{
+		Underlying: slowFiller{delay: time.Second},
+		Timeout:    10 * time.Millisecond,
+	}
+	_, err := f.Fill(Form{})
+	if err == nil {
+		t.Fatalf("expected timeout error")
+	}
+	if errgo.Cause(err) != ErrTimeout {
+		t.Errorf("got cause %v, want ErrTimeout", errgo.Cause(err))
+	}
+}
+
+func TestTimeoutFillerCompletes(t *testing.T) {
+	vals := map[string]interface{}{"region": "us-east-1"}
+	f := TimeoutFiller{
+		Underlying: slowFiller{vals: vals},
+		Timeout:    time.Minute,
+	}
+	got, err := f.Fill(Form{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["region"] != "us-east-1" {
+		t.Errorf("got %#v, want %#v", got, vals)
+	}
+}
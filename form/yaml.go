@@ -0,0 +1,74 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package form
+
+import (
+	"io"
+	"io/ioutil"
+
+	"gopkg.in/errgo.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLFiller is a Filler that fills out a form from a YAML document
+// rather than by prompting, for file-based configuration. The
+// document must hold a YAML mapping with one entry per attribute;
+// nested mappings are accepted for Tattrs attributes.
+type YAMLFiller struct {
+	// Source is read for the YAML document.
+	Source io.Reader
+}
+
+// Fill implements Filler.Fill by unmarshaling a YAML mapping from
+// f.Source and coercing each entry to its attribute's type. Entries
+// for attributes not in the form's fields are ignored.
+func (f YAMLFiller) Fill(form Form) (map[string]interface{}, error) {
+	data, err := ioutil.ReadAll(f.Source)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot read YAML document")
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errgo.Notef(err, "cannot unmarshal YAML document")
+	}
+	values := make(map[string]interface{}, len(doc))
+	for name, attr := range form.Fields {
+		v, ok := doc[name]
+		if !ok {
+			continue
+		}
+		checker, err := attr.Checker()
+		if err != nil {
+			return nil, errgo.Notef(err, "invalid field %s", name)
+		}
+		cv, err := checker.Coerce(yamlToJSON(v), []string{name})
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		values[name] = cv
+	}
+	return values, nil
+}
+
+// yamlToJSON converts the map[interface{}]interface{} values
+// produced by the YAML unmarshaler into the map[string]interface{}
+// form expected by the schema checkers, recursively.
+func yamlToJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			if s, ok := k.(string); ok {
+				m[s] = yamlToJSON(e)
+			}
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = yamlToJSON(e)
+		}
+		return v
+	}
+	return v
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"encoding/json"
+
+	"github.com/juju/schema"
+	"gopkg.in/errgo.v1"
+)
+
+// CoerceJSON unmarshals the given JSON document, which must hold
+// a JSON object, and coerces the result against the schema,
+// returning the coerced attribute values.
+//
+// Because JSON unmarshals all numbers as float64, a float value
+// with no fractional part provided for an integer attribute will
+// be accepted and coerced to int; a float with a fractional part
+// is rejected rather than silently truncated.
+func (s Fields) CoerceJSON(data []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errgo.Notef(err, "cannot unmarshal JSON")
+	}
+	m = s.PruneNull(m)
+	for name, v := range m {
+		attr, ok := s[name]
+		if !ok || (attr.Type != Tint && attr.Type != Tuint) {
+			continue
+		}
+		f, ok := v.(float64)
+		if !ok {
+			continue
+		}
+		if f != float64(int64(f)) {
+			return nil, errgo.Newf("%s: expected int, got float64(%v)", name, f)
+		}
+		m[name] = int(f)
+	}
+	sfields, sdefaults, err := s.ValidationSchema()
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	v, err := schema.FieldMap(sfields, sdefaults).Coerce(m, nil)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return v.(map[string]interface{}), nil
+}
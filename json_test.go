@@ -0,0 +1,82 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoerceJSON(t *testing.T) {
+	fields := Fields{
+		"name": {Type: Tstring},
+		"port": {Type: Tint},
+	}
+	v, err := fields.CoerceJSON([]byte(`{"name": "srv", "port": 8080}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := v["name"], "srv"; got != want {
+		t.Errorf("name: got %#v, want %#v", got, want)
+	}
+	if got, want := v["port"], 8080; got != want {
+		t.Errorf("port: got %#v, want %#v", got, want)
+	}
+}
+
+func TestCoerceJSONIntAsFloat(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	v, err := fields.CoerceJSON([]byte(`{"port": 8080.0}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := v["port"], 8080; got != want {
+		t.Errorf("port: got %#v, want %#v", got, want)
+	}
+}
+
+func TestCoerceJSONNonIntegralFloat(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	_, err := fields.CoerceJSON([]byte(`{"port": 1.5}`))
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if got, want := err.Error(), "port: expected int, got float64(1.5)"; got != want {
+		t.Errorf("got error %q, want %q", got, want)
+	}
+}
+
+func TestCoerceJSONTypeMismatch(t *testing.T) {
+	fields := Fields{
+		"port": {Type: Tint},
+	}
+	_, err := fields.CoerceJSON([]byte(`{"port": "many"}`))
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "expected int") {
+		t.Errorf("error %q does not mention the type mismatch", err)
+	}
+}
+
+func TestCoerceJSONNullUnset(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, TreatNullAsUnset: true},
+		"port":   {Type: Tint},
+	}
+	v, err := fields.CoerceJSON([]byte(`{"region": null, "port": 80}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := v["region"]; ok {
+		t.Errorf("explicitly unset attribute present in result")
+	}
+	if v["port"] != 80 {
+		t.Errorf("got port %#v, want 80", v["port"])
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gopkg.in/errgo.v1"
+)
+
+// JSONSchema returns a JSON Schema-style description of s as a
+// value that can be marshaled with encoding/json. The conversion
+// covers the subset of JSON Schema that environschema can express:
+// property types, descriptions, enumerated values, defaults,
+// required properties, patterns and integer bounds. Metadata that has no JSON Schema equivalent
+// (such as Group, Secret or the environment variables) is omitted.
+func (s Fields) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(s))
+	var required []string
+	for name, attr := range s {
+		p := make(map[string]interface{})
+		if t := jsonType(attr.Type); t != "" {
+			p["type"] = t
+		}
+		switch attr.Type {
+		case Tattrs:
+			p["additionalProperties"] = map[string]interface{}{
+				"type": "string",
+			}
+		case Tlist:
+			p["items"] = map[string]interface{}{
+				"type": "string",
+			}
+		}
+		if attr.Description != "" {
+			p["description"] = attr.Description
+		}
+		if len(attr.Values) > 0 {
+			p["enum"] = attr.Values
+		}
+		if attr.Default != nil {
+			p["default"] = attr.Default
+		}
+		if attr.Example != nil {
+			p["examples"] = []interface{}{attr.Example}
+		}
+		if attr.Pattern != "" {
+			p["pattern"] = attr.Pattern
+		}
+		if attr.Min != nil {
+			p["minimum"] = *attr.Min
+		}
+		if attr.Max != nil {
+			p["maximum"] = *attr.Max
+		}
+		properties[name] = p
+		if attr.Mandatory {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// JSONSchemaDocument returns the schema marshaled as a JSON Schema
+// draft-07 document, for consumption by web tooling that generates
+// forms from JSON Schema. It is the marshaled form of JSONSchema
+// with the draft-07 $schema identifier added.
+func (s Fields) JSONSchemaDocument() ([]byte, error) {
+	schema := s.JSONSchema()
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot marshal JSON Schema")
+	}
+	return data, nil
+}
+
+// jsonType returns the JSON Schema type name corresponding
+// to the given field type, or "" if it has none.
+func jsonType(t FieldType) string {
+	switch t {
+	case Tstring, Tunstring, Tduration:
+		return "string"
+	case Tbool:
+		return "boolean"
+	case Tint, Tuint:
+		return "integer"
+	case Tattrs:
+		return "object"
+	case Tlist:
+		return "array"
+	}
+	return ""
+}
+
+// fieldTypeFromJSON returns the field type corresponding to
+// the given JSON Schema type name.
+func fieldTypeFromJSON(t string) (FieldType, error) {
+	switch t {
+	case "string":
+		return Tstring, nil
+	case "boolean":
+		return Tbool, nil
+	case "integer":
+		return Tint, nil
+	case "object":
+		return Tattrs, nil
+	case "array":
+		return Tlist, nil
+	}
+	return "", fmt.Errorf("unsupported JSON Schema type %q", t)
+}
+
+// FieldsFromJSONSchema returns the Fields equivalent of the given
+// JSON Schema document, as produced by Fields.JSONSchema. It
+// returns an error if the document uses a construct that has no
+// environschema equivalent.
+func FieldsFromJSONSchema(schema map[string]interface{}) (Fields, error) {
+	if t, _ := schema["type"].(string); t != "object" {
+		return nil, errgo.Newf("unsupported schema type %v", schema["type"])
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return nil, errgo.New("schema has no properties")
+	}
+	required := make(map[string]bool)
+	if r, ok := schema["required"].([]string); ok {
+		for _, name := range r {
+			required[name] = true
+		}
+	} else if r, ok := schema["required"].([]interface{}); ok {
+		for _, name := range r {
+			s, ok := name.(string)
+			if !ok {
+				return nil, errgo.Newf("invalid required entry %#v", name)
+			}
+			required[s] = true
+		}
+	}
+	fields := make(Fields, len(properties))
+	for name, p0 := range properties {
+		p, ok := p0.(map[string]interface{})
+		if !ok {
+			return nil, errgo.Newf("invalid property %q", name)
+		}
+		t, _ := p["type"].(string)
+		ftype, err := fieldTypeFromJSON(t)
+		if err != nil {
+			return nil, errgo.Notef(err, "property %q", name)
+		}
+		attr := Attr{
+			Type:      ftype,
+			Mandatory: required[name],
+		}
+		attr.Description, _ = p["description"].(string)
+		if e, ok := p["enum"].([]interface{}); ok {
+			attr.Values = e
+		}
+		attr.Default = p["default"]
+		attr.Pattern, _ = p["pattern"].(string)
+		if n, ok := jsonInt(p["minimum"]); ok {
+			attr.Min = &n
+		}
+		if n, ok := jsonInt(p["maximum"]); ok {
+			attr.Max = &n
+		}
+		fields[name] = attr
+	}
+	return fields, nil
+}
+
+// jsonInt returns the given JSON Schema bound as an int. It accepts
+// both int (as produced by JSONSchema directly) and float64 (as
+// produced by unmarshaling a JSON document).
+func jsonInt(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// CheckJSONSchemaRoundTrip checks that s can be exported with
+// JSONSchema and re-imported with FieldsFromJSONSchema without
+// losing any of the features that the conversion supports. It
+// returns an error describing the first unsupported feature or
+// difference found. Presentation metadata that is deliberately
+// omitted from the JSON Schema form (such as Group, Secret and
+// environment variables) is not considered a loss.
+func (s Fields) CheckJSONSchemaRoundTrip() error {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkJSONConvertible(s[name]); err != nil {
+			return errgo.Notef(err, "attribute %q", name)
+		}
+	}
+	result, err := FieldsFromJSONSchema(s.JSONSchema())
+	if err != nil {
+		return errgo.Notef(err, "cannot re-import schema")
+	}
+	for _, name := range names {
+		attr, got := s[name], result[name]
+		if jsonType(got.Type) != jsonType(attr.Type) {
+			return errgo.Newf("attribute %q: type changed from %q to %q", name, attr.Type, got.Type)
+		}
+		if got.Description != attr.Description ||
+			got.Mandatory != attr.Mandatory ||
+			got.Pattern != attr.Pattern ||
+			!reflect.DeepEqual(got.Min, attr.Min) ||
+			!reflect.DeepEqual(got.Max, attr.Max) ||
+			!reflect.DeepEqual(got.Values, attr.Values) ||
+			!reflect.DeepEqual(got.Default, attr.Default) {
+			return errgo.Newf("attribute %q does not round-trip", name)
+		}
+	}
+	return nil
+}
+
+// checkJSONConvertible returns an error if the attribute uses a
+// feature that affects coercion semantics but cannot be expressed
+// in JSON Schema.
+func checkJSONConvertible(attr Attr) error {
+	switch {
+	case attr.Multi:
+		return errgo.New("multi values cannot be expressed in JSON Schema")
+	case attr.Step != 0:
+		return errgo.New("step cannot be expressed in JSON Schema")
+	case attr.KeyPattern != "":
+		return errgo.New("key pattern cannot be expressed in JSON Schema")
+	case attr.ValueSeparator != "":
+		return errgo.New("value separator cannot be expressed in JSON Schema")
+	case attr.DurationUnit != "":
+		return errgo.New("duration unit cannot be expressed in JSON Schema")
+	case attr.ExpandEnv:
+		return errgo.New("environment expansion cannot be expressed in JSON Schema")
+	case attr.AllowFileRef:
+		return errgo.New("file references cannot be expressed in JSON Schema")
+	}
+	return nil
+}
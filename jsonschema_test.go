@@ -0,0 +1,91 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+	min, max := 1024, 65535
+	fields := Fields{
+		"region": {
+			Type:        Tstring,
+			Description: "cloud region",
+			Mandatory:   true,
+			Pattern:     "^[a-z-]+$",
+		},
+		"port": {
+			Type: Tint,
+			Min:  &min,
+			Max:  &max,
+		},
+		"level": {
+			Type:    Tstring,
+			Values:  []interface{}{"low", "high"},
+			Default: "low",
+		},
+	}
+	if err := fields.CheckJSONSchemaRoundTrip(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestJSONSchemaRoundTripUnsupportedFeature(t *testing.T) {
+	fields := Fields{
+		"level": {
+			Type:   Tstring,
+			Values: []interface{}{"low", "high"},
+			Multi:  true,
+		},
+	}
+	err := fields.CheckJSONSchemaRoundTrip()
+	if err == nil {
+		t.Fatalf("expected error for multi-valued attribute")
+	}
+	if !strings.Contains(err.Error(), `attribute "level"`) {
+		t.Errorf("error %q does not name the offending attribute", err)
+	}
+}
+
+func TestFieldsFromJSONSchemaDocument(t *testing.T) {
+	min, max := 1024, 65535
+	fields := Fields{
+		"region": {
+			Type:    Tstring,
+			Pattern: "^[a-z-]+$",
+		},
+		"port": {
+			Type: Tint,
+			Min:  &min,
+			Max:  &max,
+		},
+	}
+	// Marshaling and unmarshaling turns the integer bounds into
+	// float64 values; they must still be read back as ints.
+	data, err := json.Marshal(fields.JSONSchema())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := FieldsFromJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["region"].Pattern != "^[a-z-]+$" {
+		t.Errorf("got pattern %q, want %q", got["region"].Pattern, "^[a-z-]+$")
+	}
+	port := got["port"]
+	if port.Min == nil || *port.Min != min {
+		t.Errorf("got min %#v, want %d", port.Min, min)
+	}
+	if port.Max == nil || *port.Max != max {
+		t.Errorf("got max %#v, want %d", port.Max, max)
+	}
+}
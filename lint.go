@@ -0,0 +1,272 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+)
+
+// LintWarning holds a warning about an attribute in a schema.
+type LintWarning struct {
+	// Name holds the name of the attribute the warning is about.
+	Name string
+
+	// Message describes the problem with the attribute.
+	Message string
+}
+
+// String returns a human-readable form of the warning.
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Name, w.Message)
+}
+
+// ReservedNames holds attribute names that Lint warns about
+// because they commonly collide with flag names or other reserved
+// words when attributes are mapped to a command line interface.
+// Clients may modify it to suit their environment.
+var ReservedNames = map[string]bool{
+	"config":  true,
+	"debug":   true,
+	"help":    true,
+	"verbose": true,
+	"version": true,
+}
+
+// KnownGroups holds the groups that Lint accepts without warning.
+// It defaults to the group constants defined by this package;
+// clients using legitimate custom groups should add them here.
+var KnownGroups = map[Group]bool{
+	JujuGroup:     true,
+	EnvironGroup:  true,
+	AccountGroup:  true,
+	ProviderGroup: true,
+}
+
+// Lint returns warnings about aspects of the schema that are legal
+// but likely to cause confusing behaviour, sorted by attribute name.
+//
+// Currently it warns about attributes with an empty Description,
+// which yield an unlabelled prompt in fillers that use the
+// description as prompt text; about mandatory attributes with
+// a default value, which can mask genuinely missing input;
+// about enumerated Values of mixed Go types, which usually
+// indicate an authoring slip; about attribute names found in
+// ReservedNames; about groups not found in KnownGroups, which
+// usually indicate a typo; and about attribute names that derive
+// the same command line flag name (see FlagName), which would
+// collide when flags are registered.
+func (s Fields) Lint() []LintWarning {
+	var warnings []LintWarning
+	for name, attr := range s {
+		if attr.Description == "" {
+			warnings = append(warnings, LintWarning{
+				Name:    name,
+				Message: "empty description",
+			})
+		}
+		if len(attr.Values) > 1 {
+			t := reflect.TypeOf(attr.Values[0])
+			for _, v := range attr.Values[1:] {
+				if reflect.TypeOf(v) != t {
+					warnings = append(warnings, LintWarning{
+						Name:    name,
+						Message: "mixed types in enumerated values",
+					})
+					break
+				}
+			}
+		}
+		if !KnownGroups[attr.Group] {
+			warnings = append(warnings, LintWarning{
+				Name:    name,
+				Message: fmt.Sprintf("uses unknown group %q", attr.Group),
+			})
+		}
+		if ReservedNames[name] {
+			warnings = append(warnings, LintWarning{
+				Name:    name,
+				Message: "name collides with reserved word",
+			})
+		}
+		if attr.Mandatory && attr.Default != nil {
+			warnings = append(warnings, LintWarning{
+				Name:    name,
+				Message: "mandatory attribute should not have a default",
+			})
+		}
+	}
+	byFlag := make(map[string][]string)
+	for name := range s {
+		flag := FlagName(name)
+		byFlag[flag] = append(byFlag[flag], name)
+	}
+	for flag, names := range byFlag {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			warnings = append(warnings, LintWarning{
+				Name:    name,
+				Message: fmt.Sprintf("maps to the same flag %q as %s", flag, others(names, name)),
+			})
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Name < warnings[j].Name
+	})
+	return warnings
+}
+
+// FlagName returns the command line flag name derived from the
+// given attribute name by flag-based fillers: the name lower-cased
+// and with underscores replaced by hyphens.
+func FlagName(attrName string) string {
+	return strings.Replace(strings.ToLower(attrName), "_", "-", -1)
+}
+
+// others returns a description of the names other than name,
+// quoted and comma-separated.
+func others(names []string, name string) string {
+	var rest []string
+	for _, n := range names {
+		if n != name {
+			rest = append(rest, fmt.Sprintf("%q", n))
+		}
+	}
+	return strings.Join(rest, ", ")
+}
+
+// Warnings returns warnings to present to the user about the given
+// attribute values, sorted by attribute name. Currently it warns
+// when a value has been supplied for an attribute that is
+// deprecated (see Attr.Deprecated) or scheduled for removal (see
+// Attr.RemovedIn).
+func (s Fields) Warnings(values map[string]interface{}) []string {
+	var warnings []string
+	for name, attr := range s {
+		if _, ok := values[name]; !ok {
+			continue
+		}
+		if attr.Deprecated {
+			warnings = append(warnings, deprecationWarning(name, attr))
+		}
+		if attr.RemovedIn != "" {
+			warnings = append(warnings, fmt.Sprintf("attribute %q will be removed in %s", name, attr.RemovedIn))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// deprecationWarning returns the warning message for the
+// given deprecated attribute.
+func deprecationWarning(name string, attr Attr) string {
+	msg := fmt.Sprintf("attribute %q is deprecated", name)
+	if attr.DeprecatedMessage != "" {
+		msg += ": " + attr.DeprecatedMessage
+	}
+	return msg
+}
+
+// HasValidTypes checks that every attribute in the schema has a type
+// for which a checker is known, returning an error naming the first
+// offending attribute in name order. It is a cheap subset of the
+// checks made by MustValid, suitable for calling at package load
+// time from init functions that cannot afford full validation.
+func (s Fields) HasValidTypes() error {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !s[name].Type.IsValid() {
+			return errgo.Newf("attribute %q has invalid type %q", name, s[name].Type)
+		}
+	}
+	return nil
+}
+
+// Check validates the schema definition itself: that a checker can
+// be derived from every attribute (covering unknown types, invalid
+// enumerated values and inconsistent options) and that any Default
+// and Example values are consistent with the attribute's type.
+// Problems are accumulated across all attributes, in name order,
+// and returned as a single error listing them all, so a unit test
+// over a static schema reports every mistake at once. It returns
+// nil if the schema is well formed.
+func (s Fields) Check() error {
+	problems := s.problems()
+	if len(problems) == 0 {
+		return nil
+	}
+	return errgo.Newf("invalid schema: %s", strings.Join(problems, "; "))
+}
+
+// problems returns a description of each problem with the schema
+// definition, in attribute name order.
+func (s Fields) problems() []string {
+	var problems []string
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attr := s[name]
+		checker, err := attr.Checker()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if attr.Default != nil {
+			if _, err := checker.Coerce(attr.Default, nil); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid default value: %v", name, err))
+			}
+		}
+		if attr.Example != nil && attr.Type != Tpath {
+			if _, err := checker.Coerce(attr.Example, nil); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid example value: %v", name, err))
+			}
+		}
+	}
+	return problems
+}
+
+// MustValid checks that the given schema is well formed - that it
+// passes Check and that it has no lint warnings - and returns it
+// unchanged. If any problems are found it panics with a message
+// listing all of them. It is intended as a guard for schemas
+// defined in package-level variables:
+//
+//	var configFields = environschema.MustValid(environschema.Fields{
+//		...
+//	})
+func MustValid(s Fields) Fields {
+	problems := s.problems()
+	for _, w := range s.Lint() {
+		problems = append(problems, w.String())
+	}
+	if len(problems) > 0 {
+		panic(fmt.Sprintf("invalid environschema.Fields: %s", strings.Join(problems, "; ")))
+	}
+	return s
+}
+
+// LintError returns an error holding the first lint warning found
+// in the schema, or nil if there are none. It can be used by
+// clients that wish to treat lint warnings as errors (strict mode).
+func (s Fields) LintError() error {
+	warnings := s.Lint()
+	if len(warnings) == 0 {
+		return nil
+	}
+	return errgo.Newf("schema lint failed: %s", warnings[0])
+}
@@ -0,0 +1,332 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lintMessages returns the messages of the warnings produced
+// by linting the given fields.
+func lintMessages(s Fields) []string {
+	var msgs []string
+	for _, w := range s.Lint() {
+		msgs = append(msgs, w.String())
+	}
+	return msgs
+}
+
+// hasLintMessage reports whether linting the given fields produces
+// a warning for the named attribute containing the given text.
+func hasLintMessage(s Fields, name, text string) bool {
+	for _, w := range s.Lint() {
+		if w.Name == name && strings.Contains(w.Message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLintEmptyDescription(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+	}
+	if !hasLintMessage(fields, "region", "empty description") {
+		t.Errorf("no empty-description warning in %q", lintMessages(fields))
+	}
+}
+
+func TestLintCleanSchema(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Description: "the region to deploy in",
+			Type:        Tstring,
+		},
+	}
+	if warnings := fields.Lint(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %q", lintMessages(fields))
+	}
+	if err := fields.LintError(); err != nil {
+		t.Errorf("unexpected strict-mode error: %v", err)
+	}
+}
+
+func TestLintErrorStrictMode(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+	}
+	err := fields.LintError()
+	if err == nil {
+		t.Fatalf("expected strict-mode error for empty description")
+	}
+	if !strings.Contains(err.Error(), "empty description") {
+		t.Errorf("error %q does not mention the empty description", err)
+	}
+}
+
+func TestWarningsRemovedIn(t *testing.T) {
+	fields := Fields{
+		"old-thing": {
+			Description: "an attribute on its way out",
+			Type:        Tstring,
+			RemovedIn:   "2.0",
+		},
+	}
+	got := fields.Warnings(map[string]interface{}{"old-thing": "x"})
+	want := []string{`attribute "old-thing" will be removed in 2.0`}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// No warning when no value is supplied for the attribute.
+	if got := fields.Warnings(nil); len(got) != 0 {
+		t.Errorf("unexpected warnings: %q", got)
+	}
+}
+
+func TestMustValid(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Description: "the region to deploy in",
+			Type:        Tstring,
+		},
+	}
+	got := MustValid(fields)
+	if !reflect.DeepEqual(got, fields) {
+		t.Errorf("got %#v, want %#v", got, fields)
+	}
+}
+
+func TestMustValidPanics(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Description: "the region to deploy in",
+			Type:        "wibble",
+		},
+	}
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected panic for invalid schema")
+		}
+		msg, ok := r.(string)
+		if !ok {
+			t.Fatalf("unexpected panic value %#v", r)
+		}
+		if !strings.Contains(msg, "invalid environschema.Fields") || !strings.Contains(msg, "region") {
+			t.Errorf("panic message %q does not list the problem", msg)
+		}
+	}()
+	MustValid(fields)
+}
+
+func TestLintMandatoryWithDefault(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Description: "the region to deploy in",
+			Type:        Tstring,
+			Mandatory:   true,
+			Default:     "us-east-1",
+		},
+	}
+	if !hasLintMessage(fields, "region", "mandatory attribute should not have a default") {
+		t.Errorf("no mandatory-with-default warning in %q", lintMessages(fields))
+	}
+}
+
+func TestLintMandatoryWithoutDefault(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Description: "the region to deploy in",
+			Type:        Tstring,
+			Mandatory:   true,
+		},
+	}
+	if warnings := fields.Lint(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %q", lintMessages(fields))
+	}
+}
+
+func TestLintMixedValueTypes(t *testing.T) {
+	fields := Fields{
+		"size": {
+			Description: "the instance size",
+			Type:        Tint,
+			Values:      []interface{}{10, "20"},
+		},
+	}
+	if !hasLintMessage(fields, "size", "mixed types in enumerated values") {
+		t.Errorf("no mixed-types warning in %q", lintMessages(fields))
+	}
+}
+
+func TestLintHomogeneousValueTypes(t *testing.T) {
+	fields := Fields{
+		"size": {
+			Description: "the instance size",
+			Type:        Tint,
+			Values:      []interface{}{10, 20},
+		},
+	}
+	if hasLintMessage(fields, "size", "mixed types") {
+		t.Errorf("unexpected mixed-types warning in %q", lintMessages(fields))
+	}
+}
+
+func TestLintReservedName(t *testing.T) {
+	fields := Fields{
+		"debug": {
+			Description: "enable debugging",
+			Type:        Tbool,
+		},
+	}
+	if !hasLintMessage(fields, "debug", "name collides with reserved word") {
+		t.Errorf("no reserved-name warning in %q", lintMessages(fields))
+	}
+	fields = Fields{
+		"region": {
+			Description: "the region to deploy in",
+			Type:        Tstring,
+		},
+	}
+	if hasLintMessage(fields, "region", "reserved word") {
+		t.Errorf("unexpected reserved-name warning in %q", lintMessages(fields))
+	}
+}
+
+func TestLintReservedNamesOverridable(t *testing.T) {
+	ReservedNames["wibble"] = true
+	defer delete(ReservedNames, "wibble")
+	fields := Fields{
+		"wibble": {
+			Description: "a custom reserved name",
+			Type:        Tstring,
+		},
+	}
+	if !hasLintMessage(fields, "wibble", "name collides with reserved word") {
+		t.Errorf("no reserved-name warning for extended set in %q", lintMessages(fields))
+	}
+}
+
+func TestLintUnknownGroup(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Type:        Tstring,
+			Description: "cloud region",
+			Group:       Group("enviorn"),
+		},
+	}
+	if !hasLintMessage(fields, "region", `uses unknown group "enviorn"`) {
+		t.Errorf("no warning about the unknown group; got %v", lintMessages(fields))
+	}
+}
+
+func TestLintKnownGroup(t *testing.T) {
+	fields := Fields{
+		"region": {
+			Type:        Tstring,
+			Description: "cloud region",
+			Group:       EnvironGroup,
+		},
+	}
+	if msgs := lintMessages(fields); len(msgs) != 0 {
+		t.Errorf("unexpected warnings: %v", msgs)
+	}
+}
+
+func TestLintCustomGroupOverridable(t *testing.T) {
+	KnownGroups[Group("custom")] = true
+	defer delete(KnownGroups, Group("custom"))
+	fields := Fields{
+		"region": {
+			Type:        Tstring,
+			Description: "cloud region",
+			Group:       Group("custom"),
+		},
+	}
+	if msgs := lintMessages(fields); len(msgs) != 0 {
+		t.Errorf("unexpected warnings: %v", msgs)
+	}
+}
+
+func TestLintFlagCollision(t *testing.T) {
+	fields := Fields{
+		"my_attr": {Type: Tstring, Description: "first"},
+		"my-attr": {Type: Tstring, Description: "second"},
+	}
+	if !hasLintMessage(fields, "my_attr", `maps to the same flag "my-attr"`) {
+		t.Errorf("no collision warning for my_attr; got %v", lintMessages(fields))
+	}
+	if !hasLintMessage(fields, "my-attr", `maps to the same flag "my-attr"`) {
+		t.Errorf("no collision warning for my-attr; got %v", lintMessages(fields))
+	}
+}
+
+func TestLintNoFlagCollision(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, Description: "cloud region"},
+		"port":   {Type: Tint, Description: "listen port"},
+	}
+	if msgs := lintMessages(fields); len(msgs) != 0 {
+		t.Errorf("unexpected warnings: %v", msgs)
+	}
+}
+
+func TestHasValidTypes(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+		"port":   {Type: Tint},
+	}
+	if err := fields.HasValidTypes(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHasValidTypesInvalid(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring},
+		"wobble": {Type: FieldType("wibble")},
+	}
+	err := fields.HasValidTypes()
+	if err == nil {
+		t.Fatalf("expected error for invalid type")
+	}
+	if !strings.Contains(err.Error(), `attribute "wobble" has invalid type "wibble"`) {
+		t.Errorf("error %q does not name the offending attribute", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, Description: "cloud region"},
+		"port":   {Type: Tint, Default: 8080, Example: "9090"},
+	}
+	if err := fields.Check(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckAccumulatesProblems(t *testing.T) {
+	fields := Fields{
+		"port":   {Type: Tint, Default: "not a number"},
+		"wobble": {Type: FieldType("wibble")},
+	}
+	err := fields.Check()
+	if err == nil {
+		t.Fatalf("expected error for malformed schema")
+	}
+	// A single error lists every problem, in attribute
+	// name order.
+	msg := err.Error()
+	if !strings.Contains(msg, "port: invalid default value") {
+		t.Errorf("error %q does not report the invalid default", err)
+	}
+	if !strings.Contains(msg, "wobble:") {
+		t.Errorf("error %q does not report the invalid type", err)
+	}
+	if !strings.Contains(msg, "; ") {
+		t.Errorf("error %q does not join the problems", err)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"reflect"
+
+	"gopkg.in/errgo.v1"
+)
+
+// Merge returns a new schema holding all the attributes of s and
+// other, so that a provider schema can be assembled from a common
+// base plus provider-specific additions. An attribute defined in
+// both schemas is an error unless the two definitions are
+// identical; use MergeOverride to let other win instead. Neither
+// receiver nor argument is modified.
+func (s Fields) Merge(other Fields) (Fields, error) {
+	result := make(Fields, len(s)+len(other))
+	for name, attr := range s {
+		result[name] = attr
+	}
+	for name, attr := range other {
+		if old, ok := result[name]; ok && !reflect.DeepEqual(old, attr) {
+			return nil, errgo.Newf("conflicting definitions of attribute %q", name)
+		}
+		result[name] = attr
+	}
+	return result, nil
+}
+
+// MergeOverride is like Merge except that an attribute defined in
+// both schemas takes its definition from other rather than being
+// an error.
+func (s Fields) MergeOverride(other Fields) Fields {
+	result := make(Fields, len(s)+len(other))
+	for name, attr := range s {
+		result[name] = attr
+	}
+	for name, attr := range other {
+		result[name] = attr
+	}
+	return result
+}
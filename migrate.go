@@ -0,0 +1,67 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+)
+
+// Migrate re-coerces the given values, which were stored under the
+// old schema, into the types required by the new schema. Each value
+// is first tried against the new attribute's checker directly; if
+// that fails, the value is coerced with the old attribute's checker
+// and the canonical old value is tried instead. Values for
+// attributes not present in the new schema are dropped.
+//
+// If any value cannot be migrated, the successfully migrated values
+// are still returned, along with an error naming every attribute
+// that failed.
+func Migrate(old, new Fields, values map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(values))
+	var failed []string
+	for name, v := range values {
+		newAttr, ok := new[name]
+		if !ok {
+			continue
+		}
+		newChecker, err := newAttr.Checker()
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		if cv, err := newChecker.Coerce(v, nil); err == nil {
+			result[name] = cv
+			continue
+		}
+		oldAttr, ok := old[name]
+		if !ok {
+			failed = append(failed, name)
+			continue
+		}
+		oldChecker, err := oldAttr.Checker()
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		ov, err := oldChecker.Coerce(v, nil)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		cv, err := newChecker.Coerce(ov, nil)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		result[name] = cv
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return result, errgo.Newf("cannot migrate attributes: %s", strings.Join(failed, ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	old := Fields{
+		"timeout": {Type: Tstring},
+	}
+	new := Fields{
+		"timeout": {Type: Tduration},
+	}
+	result, err := Migrate(old, new, map[string]interface{}{
+		"timeout": "30s",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["timeout"] != 30*time.Second {
+		t.Errorf("got %#v, want 30s", result["timeout"])
+	}
+}
+
+func TestMigrateThroughOldChecker(t *testing.T) {
+	old := Fields{
+		"port": {Type: Tunstring},
+	}
+	new := Fields{
+		"port": {Type: Tstring},
+	}
+	// The int value is rejected by the new Tstring checker
+	// directly, but the old Tunstring checker canonicalizes
+	// it to a string first.
+	result, err := Migrate(old, new, map[string]interface{}{
+		"port": 8080,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["port"] != "8080" {
+		t.Errorf("got %#v, want %q", result["port"], "8080")
+	}
+}
+
+func TestMigrateIncompatibleValue(t *testing.T) {
+	old := Fields{
+		"port":   {Type: Tstring},
+		"region": {Type: Tstring},
+	}
+	new := Fields{
+		"port":   {Type: Tint},
+		"region": {Type: Tstring},
+	}
+	result, err := Migrate(old, new, map[string]interface{}{
+		"port":   "not a number",
+		"region": "us-east-1",
+	})
+	if err == nil {
+		t.Fatalf("expected error for unmigratable value")
+	}
+	if !strings.Contains(err.Error(), "cannot migrate attributes: port") {
+		t.Errorf("error %q does not name the failing attribute", err)
+	}
+	// The successfully migrated values are still returned.
+	if result["region"] != "us-east-1" {
+		t.Errorf("got %#v, want the migrated value", result["region"])
+	}
+	if _, ok := result["port"]; ok {
+		t.Errorf("failed attribute present in result: %#v", result["port"])
+	}
+}
+
+func TestMigrateDropsRemovedAttributes(t *testing.T) {
+	old := Fields{
+		"legacy": {Type: Tstring},
+	}
+	new := Fields{}
+	result, err := Migrate(old, new, map[string]interface{}{
+		"legacy": "old-value",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("got %#v, want an empty result", result)
+	}
+}
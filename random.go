@@ -0,0 +1,151 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// RandomValue returns a randomly generated value that satisfies the
+// attribute's type and constraints, suitable for property-based
+// testing of configuration consumers. The constraints respected are
+// Values (a random allowed value is chosen, or a random non-empty
+// subset when Multi is set) and, for integer attributes, Min, Max
+// and Step: values are generated within the bounds and as multiples
+// of the step. When KeyPattern is set on a Tattrs attribute an empty
+// map is returned, as arbitrary patterns cannot be synthesized. The
+// result is returned in its raw input form rather than coerced -
+// checkers such as the duration-unit one are not idempotent - but it
+// has been validated, so coercing it always succeeds.
+func (attr Attr) RandomValue(r *rand.Rand) (interface{}, error) {
+	checker, err := attr.Checker()
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	if len(attr.Values) > 0 {
+		var raw interface{}
+		if attr.Multi {
+			chosen := make([]string, 0, len(attr.Values))
+			for _, v := range attr.Values {
+				if r.Intn(2) == 0 {
+					chosen = append(chosen, unstring(v))
+				}
+			}
+			if len(chosen) == 0 {
+				chosen = append(chosen, unstring(attr.Values[r.Intn(len(attr.Values))]))
+			}
+			raw = strings.Join(chosen, ",")
+		} else {
+			raw = attr.Values[r.Intn(len(attr.Values))]
+		}
+		if _, err := checker.Coerce(raw, nil); err != nil {
+			return nil, errgo.Mask(err)
+		}
+		return raw, nil
+	}
+	var raw interface{}
+	switch attr.Type {
+	case Tstring, Tunstring:
+		raw = randomString(r)
+	case Tbool:
+		raw = r.Intn(2) == 0
+	case Tint, Tuint:
+		n, err := randomInt(r, attr)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		raw = n
+	case Tduration:
+		unit := time.Second
+		if attr.DurationUnit != "" {
+			unit = durationUnits[attr.DurationUnit]
+		}
+		raw = time.Duration(1+r.Intn(1000)) * unit
+	case Tlist:
+		elems := make([]string, r.Intn(4))
+		for i := range elems {
+			elems[i] = randomString(r)
+		}
+		raw = elems
+	case Tattrs:
+		if attr.KeyPattern != "" {
+			raw = map[string]string{}
+			break
+		}
+		m := make(map[string]string)
+		for i := r.Intn(4); i > 0; i-- {
+			m[randomString(r)] = randomString(r)
+		}
+		raw = m
+	default:
+		return nil, errgo.Newf("cannot generate value of type %q", attr.Type)
+	}
+	if _, err := checker.Coerce(raw, nil); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return raw, nil
+}
+
+// randomInt returns a random integer satisfying the attribute's
+// Min, Max and Step constraints. When only one bound is given the
+// other is derived from it; with no bounds at all the range
+// [0, 9999] is used.
+func randomInt(r *rand.Rand, attr Attr) (int, error) {
+	min, max := 0, 9999
+	switch {
+	case attr.Min != nil && attr.Max != nil:
+		min, max = *attr.Min, *attr.Max
+	case attr.Min != nil:
+		min = *attr.Min
+		max = min + 9999
+	case attr.Max != nil:
+		max = *attr.Max
+		min = max - 9999
+	}
+	if attr.Type == Tuint && min < 0 {
+		min = 0
+	}
+	if max < min {
+		return 0, errgo.Newf("no valid value in range [%d, %d]", min, max)
+	}
+	if attr.Step == 0 {
+		return min + r.Intn(max-min+1), nil
+	}
+	// Snap the bounds inward to multiples of the step.
+	lo := min / attr.Step
+	if lo*attr.Step < min {
+		lo++
+	}
+	hi := max / attr.Step
+	if hi*attr.Step > max {
+		hi--
+	}
+	if hi < lo {
+		return 0, errgo.Newf("no multiple of %d in range [%d, %d]", attr.Step, min, max)
+	}
+	return (lo + r.Intn(hi-lo+1)) * attr.Step, nil
+}
+
+// unstring returns the string form of an enumerated value.
+func unstring(v interface{}) string {
+	s, err := unstringC{}.Coerce(v, nil)
+	if err != nil {
+		return ""
+	}
+	return s.(string)
+}
+
+// randomString returns a random short lower-case string.
+func randomString(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 4+r.Intn(8))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRandomValueSatisfiesChecker(t *testing.T) {
+	min, max := 20000, 30000
+	attrs := map[string]Attr{
+		"string":   {Type: Tstring},
+		"bool":     {Type: Tbool},
+		"int":      {Type: Tint},
+		"bounded":  {Type: Tint, Min: &min, Max: &max},
+		"stepped":  {Type: Tint, Min: &min, Max: &max, Step: 7},
+		"uint":     {Type: Tuint},
+		"duration": {Type: Tduration},
+		"unit":     {Type: Tduration, DurationUnit: "ms"},
+		"list":     {Type: Tlist},
+		"attrs":    {Type: Tattrs},
+		"enum":     {Type: Tstring, Values: []interface{}{"a", "b", "c"}},
+		"multi":    {Type: Tstring, Values: []interface{}{"a", "b", "c"}, Multi: true},
+	}
+	r := rand.New(rand.NewSource(0))
+	for name, attr := range attrs {
+		checker, err := attr.Checker()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		for i := 0; i < 20; i++ {
+			v, err := attr.RandomValue(r)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if _, err := checker.Coerce(v, nil); err != nil {
+				t.Errorf("%s: generated value %#v fails the checker: %v", name, v, err)
+			}
+		}
+	}
+}
+
+func TestRandomValueRespectsBounds(t *testing.T) {
+	min, max := 20000, 30000
+	attr := Attr{Type: Tint, Min: &min, Max: &max}
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 20; i++ {
+		v, err := attr.RandomValue(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n, ok := v.(int)
+		if !ok {
+			t.Fatalf("got %T, want int", v)
+		}
+		if n < min || n > max {
+			t.Errorf("got %d, want a value in [%d, %d]", n, min, max)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// FieldsFromStruct returns a schema derived by reflecting over the
+// fields of the given struct value (or pointer to struct), so that
+// config defined as a Go struct need not duplicate a hand-written
+// Fields map.
+//
+// The attribute name is taken from the first element of the field's
+// "environschema" tag, defaulting to the lower-cased field name.
+// Subsequent comma-separated tag elements may be "mandatory",
+// "secret", "immutable" or "group=name"; a tag of "-" omits the
+// field. The description is taken from the field's "desc" tag.
+//
+// Field types are mapped to the corresponding FieldType: string to
+// Tstring, bool to Tbool, int to Tint, map[string]string to Tattrs,
+// []string to Tlist and time.Duration to Tduration. A field of any
+// other type causes an error naming the field.
+func FieldsFromStruct(v interface{}) (Fields, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errgo.Newf("expected struct, got %T", v)
+	}
+	fields := make(Fields)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// The field is unexported.
+			continue
+		}
+		tag := f.Tag.Get("environschema")
+		if tag == "-" {
+			continue
+		}
+		ftype, err := fieldTypeOf(f.Type)
+		if err != nil {
+			return nil, errgo.Notef(err, "field %s", f.Name)
+		}
+		attr := Attr{
+			Type:        ftype,
+			Description: f.Tag.Get("desc"),
+		}
+		name := strings.ToLower(f.Name)
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		for _, part := range parts[1:] {
+			switch {
+			case part == "mandatory":
+				attr.Mandatory = true
+			case part == "secret":
+				attr.Secret = true
+			case part == "immutable":
+				attr.Immutable = true
+			case strings.HasPrefix(part, "group="):
+				attr.Group = Group(part[len("group="):])
+			default:
+				return nil, errgo.Newf("field %s: unknown tag element %q", f.Name, part)
+			}
+		}
+		fields[name] = attr
+	}
+	return fields, nil
+}
+
+// Unmarshal assigns the given attribute values, as produced by
+// coercing against a validation schema, to the fields of the struct
+// pointed to by dest. Attribute names are matched to struct fields
+// using the same "environschema" tags understood by
+// FieldsFromStruct. Values with no corresponding field are ignored;
+// a value whose type cannot be assigned to its field produces an
+// error naming the attribute.
+func Unmarshal(values map[string]interface{}, dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.Elem().Kind() != reflect.Struct {
+		return errgo.Newf("expected pointer to struct, got %T", dest)
+	}
+	dv = dv.Elem()
+	t := dv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("environschema")
+		if tag == "-" {
+			continue
+		}
+		name := strings.ToLower(f.Name)
+		if parts := strings.Split(tag, ","); parts[0] != "" {
+			name = parts[0]
+		}
+		v, ok := values[name]
+		if !ok || v == nil {
+			continue
+		}
+		vv := reflect.ValueOf(v)
+		fv := dv.Field(i)
+		if !vv.Type().AssignableTo(fv.Type()) {
+			// Allow conversion between integer types such as
+			// int and time.Duration, but nothing looser.
+			if !isIntKind(vv.Kind()) || !isIntKind(fv.Kind()) {
+				return errgo.Newf("cannot assign %T value to %s field %s", v, fv.Type(), name)
+			}
+			vv = vv.Convert(fv.Type())
+		}
+		fv.Set(vv)
+	}
+	return nil
+}
+
+// isIntKind reports whether the given kind is a signed
+// integer kind.
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// fieldTypeOf returns the field type corresponding to the
+// given Go type.
+func fieldTypeOf(t reflect.Type) (FieldType, error) {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return Tduration, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return Tstring, nil
+	case reflect.Bool:
+		return Tbool, nil
+	case reflect.Int:
+		return Tint, nil
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String {
+			return Tattrs, nil
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return Tlist, nil
+		}
+	}
+	return "", errgo.Newf("unsupported type %s", t)
+}
@@ -0,0 +1,268 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// SampleYAML writes YAML output to w, indented by indent spaces
+// that holds the attributes in attrs with descriptions found
+// in the given fields. An entry for any attribute in fields not
+// in attrs will be generated but commented out.
+func SampleYAML(w io.Writer, indent int, attrs map[string]interface{}, fields Fields) error {
+	indentStr := strings.Repeat(" ", indent)
+	orderedFields := make(attrsByGroup, 0, len(fields))
+	for name, f := range fields {
+		orderedFields = append(orderedFields, attrWithName{
+			name: name,
+			Attr: f,
+		})
+	}
+	sort.Sort(orderedFields)
+	for i, f := range orderedFields {
+		if i > 0 {
+			if _, err := w.Write(nl); err != nil {
+				return errgo.Mask(err)
+			}
+		}
+		writeSampleDescription(w, f.Attr, indentStr+"# ")
+		val, ok := attrs[f.name]
+		if ok {
+			fmt.Fprintf(w, "%s%s:", indentStr, f.name)
+			if err := indentVal(w, val, indentStr); err != nil {
+				return errgo.Mask(err)
+			}
+		} else {
+			if f.Example != nil {
+				val = f.Example
+			} else {
+				val = sampleValue(f.Type)
+			}
+			fmt.Fprintf(w, "%s# %s:", indentStr, f.name)
+			if err := indentVal(w, val, indentStr+"# "); err != nil {
+				return errgo.Mask(err)
+			}
+		}
+	}
+	return nil
+}
+
+var nl = []byte("\n")
+
+// writeSampleDescription writes the description of the given attribute
+// to w, prefixing each line with the given prefix.
+func writeSampleDescription(w io.Writer, f Attr, prefix string) {
+	previousText := false
+	doc := strings.TrimSpace(f.Description)
+	if doc != "" {
+		previousText = true
+		for _, line := range strings.Split(doc, "\n") {
+			line = strings.TrimRight(line, " \t")
+			if line == "" {
+				fmt.Fprintf(w, "%s\n", strings.TrimRight(prefix, " "))
+			} else {
+				fmt.Fprintf(w, "%s%s\n", prefix, line)
+			}
+		}
+	}
+	var attrText string
+	switch {
+	case f.Secret && f.Immutable:
+		attrText = "immutable and considered secret"
+	case f.Secret:
+		attrText = "considered secret"
+	case f.Immutable:
+		attrText = "immutable"
+	}
+	if attrText != "" {
+		if previousText {
+			fmt.Fprintf(w, "%s\n", strings.TrimRight(prefix, " "))
+		}
+		fmt.Fprintf(w, "%sThis attribute is %s.\n", prefix, attrText)
+		previousText = true
+	}
+	if previousText {
+		fmt.Fprintf(w, "%s\n", strings.TrimRight(prefix, " "))
+	}
+}
+
+// indentVal writes the given YAML-formatted value
+// to w, indenting it if necessary with the given indent string.
+func indentVal(w io.Writer, val interface{}, indentStr string) error {
+	data, err := yaml.Marshal(val)
+	if err != nil {
+		return errgo.Notef(err, "cannot marshal YAML")
+	}
+	data = data[0 : len(data)-1] // strip trailing newline.
+	if canUseSameLine(val) {
+		if _, err := fmt.Fprintf(w, " %s\n", data); err != nil {
+			return errgo.Mask(err)
+		}
+		return nil
+	}
+	if _, err := w.Write(nl); err != nil {
+		return errgo.Mask(err)
+	}
+	indentStr += "  "
+	for _, line := range strings.Split(string(data), "\n") {
+		if _, err := fmt.Fprintf(w, "%s%s\n", indentStr, line); err != nil {
+			return errgo.Mask(err)
+		}
+	}
+	return nil
+}
+
+// canUseSameLine reports whether the given value
+// can be marshaled to the same line as its key.
+func canUseSameLine(val interface{}) bool {
+	switch val.(type) {
+	case map[string]interface{}, map[string]string, []interface{}, []string:
+		return false
+	}
+	return true
+}
+
+// NamedValue associates an attribute name with a value.
+type NamedValue struct {
+	// Name holds the attribute name.
+	Name string
+
+	// Value holds the attribute's value.
+	Value interface{}
+}
+
+// OrderedValues returns the entries of values whose names are in
+// the schema, in the same deterministic order used by SampleYAML
+// (by group, with secret attributes last within a group, then by
+// name), for use when serializing to formats that do not sort keys
+// themselves.
+func (s Fields) OrderedValues(values map[string]interface{}) []NamedValue {
+	ordered := make(attrsByGroup, 0, len(values))
+	for name := range values {
+		attr, ok := s[name]
+		if !ok {
+			continue
+		}
+		ordered = append(ordered, attrWithName{
+			name: name,
+			Attr: attr,
+		})
+	}
+	sort.Sort(ordered)
+	result := make([]NamedValue, len(ordered))
+	for i, f := range ordered {
+		result[i] = NamedValue{
+			Name:  f.name,
+			Value: values[f.name],
+		}
+	}
+	return result
+}
+
+// FillTemplate returns a map holding the given values plus an entry
+// for every other attribute in the schema set to the attribute's
+// coerced Example value, or to the zero value for its type if it has
+// no usable example, producing a complete document ready for
+// editing. The returned set holds the names of the entries that
+// were derived from examples rather than provided in values.
+func (s Fields) FillTemplate(values map[string]interface{}) (map[string]interface{}, map[string]bool) {
+	result := make(map[string]interface{}, len(s))
+	fromExample := make(map[string]bool)
+	for name, attr := range s {
+		if v, ok := values[name]; ok {
+			result[name] = v
+			continue
+		}
+		result[name] = exampleValue(attr)
+		fromExample[name] = true
+	}
+	return result, fromExample
+}
+
+// exampleValue returns the attribute's coerced example value,
+// falling back to the zero value for its type.
+func exampleValue(attr Attr) interface{} {
+	if attr.Example != nil {
+		if checker, err := attr.Checker(); err == nil {
+			if v, err := checker.Coerce(attr.Example, nil); err == nil {
+				return v
+			}
+		}
+	}
+	return sampleValue(attr.Type)
+}
+
+// sampleValue returns a plausible placeholder value
+// for the given field type.
+func sampleValue(t FieldType) interface{} {
+	switch t {
+	case Tstring, Tunstring:
+		return ""
+	case Tbool:
+		return false
+	case Tint, Tuint:
+		return 0
+	case Tattrs:
+		return map[string]string{}
+	}
+	return ""
+}
+
+// attrWithName associates a field name with its attribute.
+type attrWithName struct {
+	name string
+	Attr
+}
+
+// attrsByGroup sorts attributes by group, then with
+// secret attributes last within a group, then by name.
+type attrsByGroup []attrWithName
+
+func (a attrsByGroup) Len() int {
+	return len(a)
+}
+
+func (a attrsByGroup) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+func (a attrsByGroup) Less(i, j int) bool {
+	a0, a1 := &a[i], &a[j]
+	if a0.Group != a1.Group {
+		return groupPriority(a0.Group) > groupPriority(a1.Group)
+	}
+	if a0.Secret != a1.Secret {
+		return a1.Secret
+	}
+	// An explicit order takes precedence over the name;
+	// fields with one come before fields without.
+	if a0.Order != a1.Order {
+		if a0.Order == 0 || a1.Order == 0 {
+			return a1.Order == 0
+		}
+		return a0.Order < a1.Order
+	}
+	return a0.name < a1.name
+}
+
+// groupPriority returns the priority order of the given
+// group - higher priority groups sort earlier.
+func groupPriority(g Group) int {
+	switch g {
+	case ProviderGroup:
+		return 3
+	case AccountGroup:
+		return 2
+	case EnvironGroup:
+		return 1
+	}
+	return 0
+}
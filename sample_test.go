@@ -0,0 +1,71 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedValues(t *testing.T) {
+	fields := Fields{
+		"region":   {Type: Tstring, Group: ProviderGroup},
+		"user":     {Type: Tstring, Group: AccountGroup},
+		"password": {Type: Tstring, Group: AccountGroup, Secret: true},
+	}
+	values := map[string]interface{}{
+		"password": "hunter2",
+		"user":     "alice",
+		"region":   "us-east-1",
+		"unknown":  "dropped",
+	}
+	want := []NamedValue{{
+		Name:  "region",
+		Value: "us-east-1",
+	}, {
+		Name:  "user",
+		Value: "alice",
+	}, {
+		// Secret attributes sort last within a group.
+		Name:  "password",
+		Value: "hunter2",
+	}}
+	// The ordering is deterministic, so repeated calls
+	// must give identical results.
+	for i := 0; i < 5; i++ {
+		got := fields.OrderedValues(values)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("call %d: got %#v, want %#v", i, got, want)
+		}
+	}
+}
+
+func TestFillTemplate(t *testing.T) {
+	fields := Fields{
+		"region": {Type: Tstring, Example: "us-east-1"},
+		"port":   {Type: Tint, Example: "8080"},
+		"debug":  {Type: Tbool},
+	}
+	values := map[string]interface{}{
+		"port": 90,
+	}
+	result, fromExample := fields.FillTemplate(values)
+	want := map[string]interface{}{
+		"region": "us-east-1",
+		"port":   90,
+		"debug":  false,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v, want %#v", result, want)
+	}
+	// A provided value overrides the example and is not
+	// marked as example-derived.
+	wantFrom := map[string]bool{
+		"region": true,
+		"debug":  true,
+	}
+	if !reflect.DeepEqual(fromExample, wantFrom) {
+		t.Errorf("got example-derived set %#v, want %#v", fromExample, wantFrom)
+	}
+}
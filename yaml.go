@@ -0,0 +1,67 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"sort"
+
+	"gopkg.in/errgo.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// ToYAML returns the schema definition itself marshaled as YAML,
+// with one entry per attribute holding its type, description,
+// group, flags and any enumerated values, so that a schema can be
+// stored and diffed as a file. Attributes are emitted in name
+// order, so output for the same schema is stable. Parsing the
+// result with ParseYAML reproduces an equivalent Fields value.
+func (s Fields) ToYAML() ([]byte, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot marshal schema")
+	}
+	return data, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler by checking that the
+// type string names a field type known to this package, so that a
+// typo in a schema file is reported as an unknown type rather than
+// surfacing later as an invalid checker.
+func (t *FieldType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	ft := FieldType(s)
+	if s != "" && !ft.IsValid() {
+		return errgo.Newf("unknown field type %q", s)
+	}
+	*t = ft
+	return nil
+}
+
+// ParseYAML unmarshals the given YAML document, which must hold a
+// mapping from attribute name to attribute definition, into a Fields
+// value. Each attribute is validated as it is parsed - an unknown
+// field type or an invalid enumerated value will be rejected
+// immediately with an error naming the offending attribute.
+func ParseYAML(data []byte) (Fields, error) {
+	var fields Fields
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, errgo.Notef(err, "cannot unmarshal YAML schema")
+	}
+	// Check attributes in a deterministic order so that
+	// the same schema always yields the same error.
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fields[name].Checker(); err != nil {
+			return nil, errgo.Notef(err, "invalid attribute %s", name)
+		}
+	}
+	return fields, nil
+}
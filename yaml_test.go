@@ -0,0 +1,51 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the LGPLv3, see LICENCE file for details.
+
+package environschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	data := []byte(`
+region:
+  type: string
+  description: the region to deploy in
+  mandatory: true
+port:
+  type: int
+`)
+	fields, err := ParseYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	region := fields["region"]
+	if region.Type != Tstring {
+		t.Errorf("region type: got %q, want %q", region.Type, Tstring)
+	}
+	if region.Description != "the region to deploy in" {
+		t.Errorf("region description: got %q", region.Description)
+	}
+	if !region.Mandatory {
+		t.Errorf("region is not mandatory")
+	}
+	if fields["port"].Type != Tint {
+		t.Errorf("port type: got %q, want %q", fields["port"].Type, Tint)
+	}
+}
+
+func TestParseYAMLBadType(t *testing.T) {
+	data := []byte(`
+region:
+  type: wibble
+`)
+	_, err := ParseYAML(data)
+	if err == nil {
+		t.Fatalf("expected error for unknown field type")
+	}
+	if !strings.Contains(err.Error(), `unknown field type "wibble"`) {
+		t.Errorf("error %q does not name the unknown type", err)
+	}
+}